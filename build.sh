@@ -6,18 +6,27 @@ echo "Building obsidx..."
 # Create bin directory
 mkdir -p bin
 
+# FTS5 (keyword and hybrid search) is only compiled into go-sqlite3 with this tag
+TAGS="sqlite_fts5"
+
 # Build all commands
 echo "→ Building obsidx-indexer..."
-go build -o bin/obsidx-indexer ./cmd/obsidx-indexer
+go build -tags "$TAGS" -o bin/obsidx-indexer ./cmd/obsidx-indexer
 
 echo "→ Building obsidx-recall..."
-go build -o bin/obsidx-recall ./cmd/obsidx-recall
+go build -tags "$TAGS" -o bin/obsidx-recall ./cmd/obsidx-recall
 
 echo "→ Building obsidx-rebuild..."
-go build -o bin/obsidx-rebuild ./cmd/obsidx-rebuild
+go build -tags "$TAGS" -o bin/obsidx-rebuild ./cmd/obsidx-rebuild
 
 echo "→ Building obsidx-recall-server..."
-go build -o bin/obsidx-recall-server ./cmd/obsidx-recall-server
+go build -tags "$TAGS" -o bin/obsidx-recall-server ./cmd/obsidx-recall-server
+
+echo "→ Building obsidx-gc..."
+go build -tags "$TAGS" -o bin/obsidx-gc ./cmd/obsidx-gc
+
+echo "→ Building obsidx-migrate..."
+go build -tags "$TAGS" -o bin/obsidx-migrate ./cmd/obsidx-migrate
 
 echo ""
 echo "✓ Build complete!"
@@ -27,6 +36,8 @@ echo "  bin/obsidx-indexer        # Index your vault (watch mode)"
 echo "  bin/obsidx-recall         # Search (uses daemon for speed)"
 echo "  bin/obsidx-recall-server  # Search daemon (persistent index)"
 echo "  bin/obsidx-rebuild        # Rebuild HNSW index"
+echo "  bin/obsidx-gc             # Delete old inactive chunks, reclaim space"
+echo "  bin/obsidx-migrate        # Upgrade (or --dry-run) the database schema"
 echo ""
 echo "Quick start:"
 echo "  ./start-daemon.sh ~/notes     # Start both indexer + search server"
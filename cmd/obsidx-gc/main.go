@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/sethfair/obsidx/internal/store"
+)
+
+var (
+	dbPath    = flag.String("db", ".obsidian-index/obsidx.db", "Path to SQLite database")
+	retention = flag.Duration("retention", 7*24*time.Hour, "Keep inactive chunks (and their reusable embeddings) and change log entries this long")
+	vacuum    = flag.Bool("vacuum", false, "VACUUM afterwards and truncate the WAL, returning freed space to the filesystem")
+)
+
+func main() {
+	flag.Parse()
+
+	ctx := context.Background()
+
+	st, err := store.Open(*dbPath, 0)
+	if err != nil {
+		log.Fatalf("Open store: %v", err)
+	}
+	defer st.Close()
+
+	before, err := st.GetSpaceUsage(ctx)
+	if err != nil {
+		log.Fatalf("Space usage: %v", err)
+	}
+	diskBefore := diskSize(*dbPath)
+
+	cutoff := time.Now().Add(-*retention)
+	log.Printf("Collecting garbage older than %s...", cutoff.Format(time.RFC3339))
+	stats, err := st.CollectGarbage(ctx, cutoff)
+	if err != nil {
+		log.Fatalf("Collect garbage: %v", err)
+	}
+	log.Printf("Deleted %d inactive chunks, %d embeddings, %d change log entries",
+		stats.Chunks, stats.Embeddings, stats.Changes)
+
+	after, err := st.GetSpaceUsage(ctx)
+	if err != nil {
+		log.Fatalf("Space usage: %v", err)
+	}
+	log.Printf("Freed %s inside the database (%s free of %s)",
+		formatBytes(after.FreeBytes()-before.FreeBytes()), formatBytes(after.FreeBytes()), formatBytes(after.FileBytes()))
+
+	if !*vacuum {
+		if after.FreePages > 0 {
+			log.Println("Run with --vacuum to return free space to the filesystem")
+		}
+		return
+	}
+
+	log.Println("Vacuuming...")
+	start := time.Now()
+	if err := st.Vacuum(ctx); err != nil {
+		log.Fatalf("Vacuum: %v", err)
+	}
+	diskAfter := diskSize(*dbPath)
+	log.Printf("Vacuum complete in %v: %s -> %s on disk (reclaimed %s)",
+		time.Since(start).Round(time.Millisecond), formatBytes(diskBefore), formatBytes(diskAfter),
+		formatBytes(diskBefore-diskAfter))
+}
+
+// diskSize is the size of the database and its write-ahead log
+func diskSize(path string) int64 {
+	var total int64
+	for _, p := range []string{path, path + "-wal"} {
+		if fi, err := os.Stat(p); err == nil {
+			total += fi.Size()
+		}
+	}
+	return total
+}
+
+func formatBytes(n int64) string {
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+	switch {
+	case abs >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case abs >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case abs >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
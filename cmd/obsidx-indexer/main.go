@@ -8,13 +8,17 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/sethfair/obsidx/internal/ann"
+	"github.com/sethfair/obsidx/internal/chunker"
 	"github.com/sethfair/obsidx/internal/config"
 	"github.com/sethfair/obsidx/internal/embed"
 	"github.com/sethfair/obsidx/internal/indexer"
+	"github.com/sethfair/obsidx/internal/snapshot"
 	"github.com/sethfair/obsidx/internal/store"
 	"github.com/sethfair/obsidx/internal/watcher"
 )
@@ -27,6 +31,20 @@ var (
 	embedModel   = flag.String("model", "nomic-embed-text", "Ollama embedding model (nomic-embed-text, all-minilm, etc)")
 	watchMode    = flag.Bool("watch", false, "Watch mode: continuously monitor for changes")
 	debounceMs   = flag.Int("debounce", 500, "Debounce time in milliseconds for watch mode")
+	concurrency  = flag.Int("concurrency", indexer.DefaultConcurrency, "Files parsed and embedded in parallel during a full index (writes stay serial)")
+	snapFile     = flag.String("snapshot", "", "Vector snapshot for fast startup (default: "+snapshot.FileName+" beside --db; \"off\" disables)")
+	contextProps = flag.String("context-properties", "", "Comma-separated front matter properties embedded with every chunk of a note (e.g. title,aliases)")
+	contextTmpl  = flag.String("context-template", "", "Go template embedded ahead of every chunk, e.g. '{{.Title}} > {{.HeadingPath}}' (\"default\" for title, aliases and heading path)")
+	chunkTarget  = flag.Int("chunk-target-tokens", chunker.DefaultTargetTokens, "Close a chunk at the next paragraph or sentence once it reaches this many tokens")
+	chunkMax     = flag.Int("chunk-max-tokens", chunker.DefaultMaxTokens, "Never grow a chunk past this many tokens, except to keep a code block, table, callout or list whole")
+	chunkOverlap = flag.Int("chunk-overlap-tokens", chunker.DefaultOverlapTokens, "Repeat up to this many trailing tokens of a chunk at the start of the next in the same section")
+	chunkSplit   = flag.String("chunk-split", string(chunker.SplitSentence), "Finest boundary to split a section at: heading, paragraph or sentence")
+	chunkerName  = flag.String("chunker", chunker.DefaultStrategy, "Chunking strategy for the vault: "+strings.Join(chunker.Strategies(), ", "))
+	queryPrefix  = flag.String("query-prefix", "auto", "Prefix the recall server embeds queries behind, recorded in the index (\"auto\" for the model's, \"\" for none)")
+	docPrefix    = flag.String("document-prefix", "auto", "Prefix every chunk is embedded behind (\"auto\" for the model's, \"\" for none)")
+	chunkFolders = flag.String("folder-chunkers", "", "Comma-separated folder=strategy overrides, relative to the vault (e.g. Journal=note,Clippings=window)")
+	gcInterval   = flag.Duration("gc-interval", 0, "Watch mode: delete expired inactive chunks this often (0 disables; see obsidx-gc)")
+	gcRetention  = flag.Duration("gc-retention", 7*24*time.Hour, "Keep inactive chunks and change log entries this long before garbage collection")
 )
 
 func main() {
@@ -35,6 +53,10 @@ func main() {
 	if *vaultDir == "" {
 		log.Fatal("--vault is required")
 	}
+	split, err := chunker.ParseSplitLevel(*chunkSplit)
+	if err != nil {
+		log.Fatalf("--chunk-split: %v", err)
+	}
 
 	// Ensure db directory exists
 	if err := os.MkdirAll(filepath.Dir(*dbPath), 0755); err != nil {
@@ -73,6 +95,17 @@ func main() {
 	actualDim := len(testVec)
 	log.Printf("Connected to Ollama - model: %s, dimension: %d\n", *embedModel, actualDim)
 
+	// Asymmetric models embed queries and documents behind task prefixes
+	prefixes := embedder.Prefixes()
+	if *queryPrefix != "auto" {
+		prefixes.Query = *queryPrefix
+	}
+	if *docPrefix != "auto" {
+		prefixes.Document = *docPrefix
+	}
+	embedder.SetPrefixes(prefixes)
+	log.Printf("Task prefixes - query: %q, document: %q\n", prefixes.Query, prefixes.Document)
+
 	// Initialize store
 	st, err := store.Open(*dbPath, actualDim)
 	if err != nil {
@@ -80,13 +113,32 @@ func main() {
 	}
 	defer st.Close()
 
-	// Initialize ANN index (exact scan — see ann.BruteForce doc comment)
-	annIndex := ann.NewBruteForce(actualDim)
+	// Initialize ANN index (exact scan — see ann.BruteForce doc comment),
+	// rebuilding if the model changed
+	snapshotPath := snapshot.Path(*snapFile, *dbPath)
+	annIndex, err := checkAndRebuild(ctx, st, actualDim, *embedModel, snapshotPath)
+	if err != nil {
+		log.Fatalf("Check/rebuild index: %v", err)
+	}
 	defer annIndex.Close()
 
-	// Check if we need to rebuild index
-	if err := checkAndRebuild(ctx, st, annIndex, actualDim, *embedModel); err != nil {
-		log.Fatalf("Check/rebuild index: %v", err)
+	// saveSnapshot records the index as of the latest change. The indexer
+	// is the only writer and indexMu keeps watch callbacks out, so the seq
+	// read now matches the vectors held.
+	var indexMu sync.Mutex
+	saveSnapshot := func() {
+		if snapshotPath == "" {
+			return
+		}
+		indexMu.Lock()
+		defer indexMu.Unlock()
+		seq, err := st.GetLatestChangeSeq(context.Background())
+		if err == nil {
+			err = snapshot.Save(annIndex, snapshotPath, *embedModel, seq)
+		}
+		if err != nil {
+			log.Printf("Warning: could not save vector snapshot: %v", err)
+		}
 	}
 
 	// Load weight configuration
@@ -107,6 +159,45 @@ func main() {
 	// Create indexer
 	idx := indexer.New(st, embedder, annIndex, *vaultDir)
 	idx.SetWeightConfig(weightCfg)
+	idx.SetConcurrency(*concurrency)
+	idx.SetChunkOptions(chunker.ChunkOptions{
+		TargetTokens:  *chunkTarget,
+		MaxTokens:     *chunkMax,
+		OverlapTokens: *chunkOverlap,
+		Split:         split,
+		Tokenizer:     chunker.EstimateTokenizer{},
+	})
+	if *contextProps != "" {
+		var keys []string
+		for _, key := range strings.Split(*contextProps, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+		idx.SetContextProperties(keys)
+	}
+	tmpl := *contextTmpl
+	if tmpl == "default" {
+		tmpl = indexer.DefaultContextTemplate
+	}
+	if err := idx.SetContextTemplate(tmpl); err != nil {
+		log.Fatalf("--context-template: %v", err)
+	}
+	if err := idx.SetChunkStrategy("", *chunkerName); err != nil {
+		log.Fatalf("--chunker: %v", err)
+	}
+	for _, rule := range strings.Split(*chunkFolders, ",") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		folder, name, ok := strings.Cut(rule, "=")
+		if !ok || strings.TrimSpace(folder) == "" {
+			log.Fatalf("--folder-chunkers: %q is not folder=strategy", rule)
+		}
+		if err := idx.SetChunkStrategy(strings.TrimSpace(folder), strings.TrimSpace(name)); err != nil {
+			log.Fatalf("--folder-chunkers: %v", err)
+		}
+	}
 
 	if *watchMode {
 		// Watch mode: monitor for changes
@@ -114,6 +205,8 @@ func main() {
 
 		changeCount := 0
 		w, err := watcher.New(func(path string) {
+			indexMu.Lock()
+			defer indexMu.Unlock()
 			changeCount++
 			relPath, _ := filepath.Rel(*vaultDir, path)
 			log.Printf("📝 [%d] Detected change: %s", changeCount, relPath)
@@ -122,6 +215,27 @@ func main() {
 			} else {
 				log.Printf("✓ Re-indexed: %s\n", relPath)
 			}
+		}, func(path string, isDir bool) {
+			indexMu.Lock()
+			defer indexMu.Unlock()
+			changeCount++
+			relPath, _ := filepath.Rel(*vaultDir, path)
+			if isDir {
+				log.Printf("🗑  [%d] Detected folder removal: %s", changeCount, relPath)
+				removed, err := idx.RemoveDir(ctx, path)
+				if err != nil {
+					log.Printf("❌ Error removing %s: %v\n", relPath, err)
+				} else {
+					log.Printf("✓ Removed %d notes under %s\n", removed, relPath)
+				}
+				return
+			}
+			log.Printf("🗑  [%d] Detected removal: %s", changeCount, relPath)
+			if err := idx.RemoveFile(ctx, path); err != nil {
+				log.Printf("❌ Error removing %s: %v\n", relPath, err)
+			} else {
+				log.Printf("✓ Removed from index: %s\n", relPath)
+			}
 		}, time.Duration(*debounceMs)*time.Millisecond)
 		if err != nil {
 			log.Fatalf("Create watcher: %v", err)
@@ -137,6 +251,8 @@ func main() {
 		// Get stats after initial index
 		activeCount, _ := st.GetActiveChunkCount(ctx)
 		log.Printf("✓ Initial index complete - %d active chunks indexed\n", activeCount)
+		saveSnapshot()
+		defer saveSnapshot()
 		log.Println("👀 Watching for changes... (Press Ctrl+C to stop)")
 		log.Println("")
 
@@ -155,6 +271,29 @@ func main() {
 			}
 		}()
 
+		// Periodically drop chunks deactivated by edits (no VACUUM here:
+		// it blocks writers; run obsidx-gc --vacuum for that)
+		if *gcInterval > 0 {
+			gcTicker := time.NewTicker(*gcInterval)
+			defer gcTicker.Stop()
+			go func() {
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-gcTicker.C:
+						stats, err := st.CollectGarbage(ctx, time.Now().Add(-*gcRetention))
+						if err != nil {
+							log.Printf("❌ Garbage collection failed: %v\n", err)
+						} else if stats.Chunks > 0 || stats.Embeddings > 0 || stats.Changes > 0 {
+							log.Printf("🧹 Garbage collected %d inactive chunks, %d embeddings, %d change log entries\n",
+								stats.Chunks, stats.Embeddings, stats.Changes)
+						}
+					}
+				}
+			}()
+		}
+
 		// Start watching
 		if err := w.Watch(ctx, *vaultDir); err != nil && err != context.Canceled {
 			log.Fatalf("Watch error: %v", err)
@@ -165,12 +304,15 @@ func main() {
 		if err := idx.IndexVault(ctx); err != nil {
 			log.Fatalf("Index vault: %v", err)
 		}
+		saveSnapshot()
 		log.Println("Indexing complete")
 	}
 }
 
-// checkAndRebuild checks if index needs rebuilding and rebuilds if necessary
-func checkAndRebuild(ctx context.Context, st *store.SQLite, annIndex ann.Index, dim int, model string) error {
+// checkAndRebuild returns the search index for the store's embeddings,
+// rebuilding from SQLite when the model or dimension changed and
+// otherwise loading through the vector snapshot.
+func checkAndRebuild(ctx context.Context, st *store.SQLite, dim int, model, snapshotPath string) (*ann.BruteForce, error) {
 	// Check index metadata
 	storedDim, _ := st.GetIndexMetaInt(ctx, "dim")
 	storedModel, _ := st.GetIndexMeta(ctx, "embedding_model_name")
@@ -186,81 +328,31 @@ func checkAndRebuild(ctx context.Context, st *store.SQLite, annIndex ann.Index,
 	}
 
 	if needsRebuild {
-		return rebuildIndex(ctx, st, annIndex, dim, model)
+		annIndex := ann.NewBruteForce(dim)
+		if err := rebuildIndex(ctx, st, annIndex, dim, model); err != nil {
+			return nil, err
+		}
+		return annIndex, nil
 	}
 
 	// Load existing vectors into the search index
 	log.Println("Loading existing embeddings into search index...")
-	rows, err := st.StreamActiveEmbeddings(ctx)
+	loaded, _, err := snapshot.Load(ctx, st, snapshotPath, dim, model, ann.Options{})
 	if err != nil {
-		return fmt.Errorf("stream embeddings: %w", err)
-	}
-	defer rows.Close()
-
-	count := 0
-	for rows.Next() {
-		var id uint64
-		var vecBlob []byte
-		if err := rows.Scan(&id, &vecBlob); err != nil {
-			return fmt.Errorf("scan row: %w", err)
-		}
-
-		vec, err := store.BytesToFloat32(vecBlob)
-		if err != nil {
-			return fmt.Errorf("decode vec: %w", err)
-		}
-
-		// Skip unloadable rows (e.g. zero-norm vectors in an old database)
-		// instead of aborting startup; log so they get noticed.
-		if err := annIndex.Add(id, vec); err != nil {
-			log.Printf("Skipping chunk %d: %v", id, err)
-			continue
-		}
-		count++
-
-		if count%1000 == 0 {
-			log.Printf("Loaded %d vectors...\n", count)
-		}
+		return nil, err
 	}
-
-	log.Printf("Loaded %d vectors into search index\n", count)
-	return nil
+	log.Printf("Loaded %d vectors into search index\n", loaded.Size())
+	return loaded, nil
 }
 
-// rebuildIndex rebuilds the search index from SQLite
+// rebuildIndex rebuilds the search index from SQLite (snapshot.Stream)
+// and records what it was built for
 func rebuildIndex(ctx context.Context, st *store.SQLite, annIndex ann.Index, dim int, model string) error {
 	log.Println("Rebuilding search index from SQLite...")
 
-	rows, err := st.StreamActiveEmbeddings(ctx)
+	count, err := snapshot.Stream(ctx, st, annIndex)
 	if err != nil {
-		return fmt.Errorf("stream embeddings: %w", err)
-	}
-	defer rows.Close()
-
-	count := 0
-	for rows.Next() {
-		var id uint64
-		var vecBlob []byte
-		if err := rows.Scan(&id, &vecBlob); err != nil {
-			return fmt.Errorf("scan row: %w", err)
-		}
-
-		vec, err := store.BytesToFloat32(vecBlob)
-		if err != nil {
-			return fmt.Errorf("decode vec: %w", err)
-		}
-
-		// Skip unloadable rows (e.g. zero-norm vectors in an old database)
-		// instead of aborting startup; log so they get noticed.
-		if err := annIndex.Add(id, vec); err != nil {
-			log.Printf("Skipping chunk %d: %v", id, err)
-			continue
-		}
-		count++
-
-		if count%1000 == 0 {
-			log.Printf("Indexed %d vectors...\n", count)
-		}
+		return err
 	}
 
 	// Update metadata
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/sethfair/obsidx/internal/store"
+)
+
+var (
+	dbPath = flag.String("db", ".obsidian-index/obsidx.db", "Path to SQLite database")
+	dryRun = flag.Bool("dry-run", false, "Run pending migrations in a transaction that is rolled back, and report them")
+)
+
+func main() {
+	flag.Parse()
+
+	from, steps, err := store.Migrate(context.Background(), *dbPath, *dryRun)
+	if err != nil {
+		log.Fatalf("Migrate: %v", err)
+	}
+
+	if len(steps) == 0 {
+		log.Printf("Schema is up to date (version %d)", from)
+		return
+	}
+
+	verb := "Applied"
+	if *dryRun {
+		verb = "Would apply"
+	}
+	for _, s := range steps {
+		log.Printf("%s migration %d: %s", verb, s.Version, s.Name)
+	}
+	if *dryRun {
+		log.Printf("Dry run: schema version %d -> %d, nothing changed", from, store.SchemaVersion())
+	} else {
+		log.Printf("Schema version %d -> %d", from, store.SchemaVersion())
+	}
+}
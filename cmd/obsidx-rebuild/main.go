@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/sethfair/obsidx/internal/ann"
+	"github.com/sethfair/obsidx/internal/snapshot"
 	"github.com/sethfair/obsidx/internal/store"
 )
 
@@ -15,6 +16,7 @@ var (
 	dbPath    = flag.String("db", ".obsidian-index/obsidx.db", "Path to SQLite database")
 	dimension = flag.Int("dim", 768, "Embedding dimension")
 	modelName = flag.String("model", "default", "Embedding model name")
+	snapFile  = flag.String("snapshot", "", "Vector snapshot to write (default: "+snapshot.FileName+" beside --db; \"off\" disables)")
 )
 
 func main() {
@@ -33,11 +35,23 @@ func main() {
 	annIndex := ann.NewBruteForce(*dimension)
 	defer annIndex.Close()
 
-	// Rebuild
+	// Rebuild. The seq is read first so the snapshot never claims changes
+	// the streamed vectors might not include.
+	seq, err := st.GetLatestChangeSeq(ctx)
+	if err != nil {
+		log.Fatalf("Get change seq: %v", err)
+	}
 	if err := rebuild(ctx, st, annIndex); err != nil {
 		log.Fatalf("Rebuild failed: %v", err)
 	}
 
+	if path := snapshot.Path(*snapFile, *dbPath); path != "" {
+		if err := annIndex.Save(path, *modelName, seq); err != nil {
+			log.Fatalf("Save snapshot: %v", err)
+		}
+		log.Printf("Vector snapshot written to %s", path)
+	}
+
 	log.Println("Rebuild complete!")
 }
 
@@ -53,43 +67,10 @@ func rebuild(ctx context.Context, st *store.SQLite, annIndex ann.Index) error {
 	log.Printf("Active chunks to rebuild: %d\n", activeCount)
 
 	// Stream and add all active embeddings
-	rows, err := st.StreamActiveEmbeddings(ctx)
-	if err != nil {
-		return fmt.Errorf("stream embeddings: %w", err)
-	}
-	defer rows.Close()
-
-	count := 0
 	startTime := time.Now()
-
-	for rows.Next() {
-		var id uint64
-		var vecBlob []byte
-		if err := rows.Scan(&id, &vecBlob); err != nil {
-			return fmt.Errorf("scan row: %w", err)
-		}
-
-		vec, err := store.BytesToFloat32(vecBlob)
-		if err != nil {
-			return fmt.Errorf("decode vec for chunk %d: %w", id, err)
-		}
-
-		// Skip unloadable rows (e.g. zero-norm vectors) but keep validating the rest.
-		if err := annIndex.Add(id, vec); err != nil {
-			log.Printf("Skipping chunk %d: %v", id, err)
-			continue
-		}
-
-		count++
-		if count%1000 == 0 {
-			elapsed := time.Since(startTime)
-			rate := float64(count) / elapsed.Seconds()
-			log.Printf("Progress: %d/%d vectors (%.1f vec/sec)\n", count, activeCount, rate)
-		}
-	}
-
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("iteration error: %w", err)
+	count, err := snapshot.Stream(ctx, st, annIndex)
+	if err != nil {
+		return err
 	}
 
 	elapsed := time.Since(startTime)
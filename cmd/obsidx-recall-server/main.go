@@ -3,39 +3,84 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/sethfair/obsidx/internal/ann"
 	"github.com/sethfair/obsidx/internal/embed"
 	"github.com/sethfair/obsidx/internal/rank"
+	"github.com/sethfair/obsidx/internal/snapshot"
 	"github.com/sethfair/obsidx/internal/store"
 )
 
 var (
-	dbPath     = flag.String("db", ".obsidian-index/obsidx.db", "Path to SQLite database")
-	port       = flag.Int("port", 8765, "HTTP server port")
-	ollamaURL  = flag.String("ollama-url", "http://localhost:11434", "Ollama API endpoint")
-	embedModel = flag.String("model", "nomic-embed-text", "Ollama embedding model")
+	dbPath      = flag.String("db", ".obsidian-index/obsidx.db", "Path to SQLite database")
+	port        = flag.Int("port", 8765, "HTTP server port")
+	ollamaURL   = flag.String("ollama-url", "http://localhost:11434", "Ollama API endpoint")
+	embedModel  = flag.String("model", "nomic-embed-text", "Ollama embedding model")
+	syncEvery   = flag.Duration("sync-interval", 2*time.Second, "How often to pick up index changes from the database (0 disables)")
+	quantize    = flag.String("quantize", "none", "Scan quantization: none (exact float32), int8 or binary; candidates are rescored exactly")
+	rescore     = flag.Int("rescore", 0, "Quantized candidates kept per result for exact rescoring (0 = default for the mode)")
+	queryPrefix = flag.String("query-prefix", "auto", "Prefix queries are embedded behind (\"auto\" for the one the index was built with, \"\" for none)")
+	snapFile    = flag.String("snapshot", "", "Vector snapshot for fast startup (default: "+snapshot.FileName+" beside --db; \"off\" disables)")
 )
 
 type Server struct {
 	store    *store.SQLite
 	embedder embed.Embedder
-	annIndex ann.Index
+	dim      int
+	model    string
 	ctx      context.Context
+
+	// queryPrefix overrides the query prefix recorded by the indexer
+	// unless "auto"
+	queryPrefix string
+
+	// snapshotPath is the vector snapshot file ("" when disabled)
+	snapshotPath string
+	annOpts      ann.Options
+
+	// syncMu serializes change-log replay with snapshot saves
+	syncMu sync.Mutex
+
+	// mu guards annIndex (swapped wholesale on reload) and lastSeq, the
+	// last chunk_changes seq applied.
+	mu       sync.RWMutex
+	annIndex ann.Index
+	lastSeq  int64
 }
 
+// Retrieval modes for SearchRequest.Mode
+const (
+	modeVector  = "vector"  // cosine similarity only (default)
+	modeKeyword = "keyword" // BM25 over the FTS5 index only
+	modeHybrid  = "hybrid"  // both, merged with reciprocal rank fusion
+)
+
 type SearchRequest struct {
 	Query      string `json:"query"`
 	TopN       int    `json:"top_n"`
 	CandidateK int    `json:"candidate_k"`
+	Mode       string `json:"mode,omitempty"`
+	// Hybrid fusion tuning: per-list RRF weights (default 1) and the RRF
+	// damping constant (default 60)
+	VectorWeight  float32 `json:"vector_weight,omitempty"`
+	KeywordWeight float32 `json:"keyword_weight,omitempty"`
+	RRFK          int     `json:"rrf_k,omitempty"`
+	// Link-proximity boost: results linked to or from the best GraphSeeds
+	// notes (default 5) gain up to GraphWeight. 0 (the default) disables it.
+	GraphWeight float32 `json:"graph_weight,omitempty"`
+	GraphSeeds  int     `json:"graph_seeds,omitempty"`
+	// Filter restricts results by note metadata before top-k selection
+	Filter *store.ChunkFilter `json:"filter,omitempty"`
 }
 
 type SearchResponse struct {
@@ -45,24 +90,54 @@ type SearchResponse struct {
 }
 
 type ResultItem struct {
-	Score          float32  `json:"score"`
-	Path           string   `json:"path"`
-	HeadingPath    string   `json:"heading_path"`
-	Status         string   `json:"status"`
-	Scope          string   `json:"scope"`
-	StartLine      int      `json:"start_line"`
-	EndLine        int      `json:"end_line"`
-	Content        string   `json:"content"`
-	CategoryWeight float32  `json:"category_weight"`
-	Tags           []string `json:"tags"`
+	Score          float32          `json:"score"`
+	Path           string           `json:"path"`
+	HeadingPath    string           `json:"heading_path"`
+	Status         string           `json:"status"`
+	Scope          string           `json:"scope"`
+	StartLine      int              `json:"start_line"`
+	EndLine        int              `json:"end_line"`
+	Content        string           `json:"content"`
+	CategoryWeight float32          `json:"category_weight"`
+	Tags           []string         `json:"tags"`
+	ChunkTags      []string         `json:"chunk_tags,omitempty"` // inline tags in this chunk
+	Explain        rank.Explanation `json:"explain"`
+}
+
+// LinksResponse is the note graph around one note, for GET /links
+type LinksResponse struct {
+	Path       string     `json:"path"`
+	Outgoing   []LinkItem `json:"outgoing"`   // links to indexed notes
+	Unresolved []LinkItem `json:"unresolved"` // links to attachments or notes not in the index
+	Backlinks  []LinkItem `json:"backlinks"`  // links from other notes
+	Error      string     `json:"error,omitempty"`
+}
+
+type LinkItem struct {
+	SourcePath string     `json:"source_path"`
+	Line       int        `json:"line"`
+	LinkType   string     `json:"link_type"` // wikilink, embed or markdown
+	Target     string     `json:"target"`    // as written
+	Heading    string     `json:"heading,omitempty"`
+	Alias      string     `json:"alias,omitempty"`
+	TargetPath string     `json:"target_path,omitempty"` // resolved note
+	Chunk      *LinkChunk `json:"chunk,omitempty"`       // the linking chunk; absent in front matter
+}
+
+type LinkChunk struct {
+	HeadingPath string `json:"heading_path"`
+	StartLine   int    `json:"start_line"`
+	EndLine     int    `json:"end_line"`
 }
 
 type TimingInfo struct {
-	EmbedMs  int64 `json:"embed_ms"`
-	SearchMs int64 `json:"search_ms"`
-	FetchMs  int64 `json:"fetch_ms"`
-	RerankMs int64 `json:"rerank_ms"`
-	TotalMs  int64 `json:"total_ms"`
+	FilterMs  int64 `json:"filter_ms"`
+	EmbedMs   int64 `json:"embed_ms"`
+	SearchMs  int64 `json:"search_ms"`
+	KeywordMs int64 `json:"keyword_ms"`
+	FetchMs   int64 `json:"fetch_ms"`
+	RerankMs  int64 `json:"rerank_ms"`
+	TotalMs   int64 `json:"total_ms"`
 }
 
 func main() {
@@ -98,6 +173,9 @@ func main() {
 
 	storedModel, _ := st.GetIndexMeta(ctx, "embedding_model_name")
 	log.Printf("📊 Index: dim=%d, model=%s", storedDim, storedModel)
+	if !st.HasKeywordSearch() {
+		log.Printf("⚠️  Keyword/hybrid search disabled (binary built without -tags sqlite_fts5)")
+	}
 
 	// Initialize embedder
 	log.Printf("🔌 Connecting to Ollama at %s...", *ollamaURL)
@@ -107,34 +185,46 @@ func main() {
 	}
 	log.Printf("✓ Connected to Ollama")
 
+	quant, err := ann.ParseQuantization(*quantize)
+	if err != nil {
+		log.Fatalf("Invalid --quantize: %v", err)
+	}
+
 	// Build exact-search index (one time!). Exact scan replaced HNSW after
 	// the graph showed near-zero recall on this vault's embeddings — see
 	// ann.BruteForce doc comment.
-	log.Printf("🏗️  Building exact-search index...")
-	annIndex := ann.NewBruteForce(storedDim)
-	defer annIndex.Close()
+	log.Printf("🏗️  Building exact-search index (quantization: %s)...", quant)
+	srv := &Server{
+		store:    st,
+		embedder: embedder,
+		dim:      storedDim,
+		model:    storedModel,
+		ctx:      ctx,
 
-	// Load vectors from SQLite
-	if err := loadIndex(ctx, st, annIndex); err != nil {
+		snapshotPath: snapshot.Path(*snapFile, *dbPath),
+		annOpts:      ann.Options{Quantization: quant, Rescore: *rescore},
+		queryPrefix:  *queryPrefix,
+	}
+	if err := srv.reload(); err != nil {
 		log.Fatalf("Failed to load index: %v", err)
 	}
+	defer func() { srv.index().Close() }()
 
 	log.Printf("✅ Server ready - index loaded and cached in memory")
 	log.Printf("   Searches will be <100ms (no index rebuild!)")
 	log.Printf("")
 
-	// Create server
-	srv := &Server{
-		store:    st,
-		embedder: embedder,
-		annIndex: annIndex,
-		ctx:      ctx,
+	// Track the indexer's writes so searches see new and deleted notes
+	// without a restart.
+	if *syncEvery > 0 {
+		go srv.syncLoop(*syncEvery)
 	}
 
 	// Setup HTTP handlers
 	http.HandleFunc("/search", srv.handleSearch)
 	http.HandleFunc("/health", srv.handleHealth)
 	http.HandleFunc("/stats", srv.handleStats)
+	http.HandleFunc("/links", srv.handleLinks)
 
 	// Start server
 	httpServer := &http.Server{
@@ -158,6 +248,14 @@ func main() {
 		log.Printf("Server shutdown error: %v", err)
 	}
 
+	if srv.snapshotPath != "" {
+		if err := srv.saveSnapshot(); err != nil {
+			log.Printf("⚠️  Failed to save vector snapshot: %v", err)
+		} else {
+			log.Printf("✓ Saved vector snapshot to %s", srv.snapshotPath)
+		}
+	}
+
 	log.Println("✓ Server stopped")
 }
 
@@ -183,26 +281,101 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	if req.CandidateK <= 0 {
 		req.CandidateK = 200
 	}
+	if req.VectorWeight <= 0 {
+		req.VectorWeight = 1
+	}
+	if req.KeywordWeight <= 0 {
+		req.KeywordWeight = 1
+	}
+	if req.GraphWeight < 0 {
+		req.GraphWeight = 0
+	}
+	if req.GraphSeeds <= 0 {
+		req.GraphSeeds = rank.DefaultGraphSeeds
+	}
+
+	switch req.Mode {
+	case "":
+		req.Mode = modeVector
+	case modeVector, modeKeyword, modeHybrid:
+	default:
+		s.sendError(w, fmt.Sprintf("Invalid mode %q (want vector, keyword or hybrid)", req.Mode), http.StatusBadRequest)
+		return
+	}
+	if req.Mode != modeVector && !s.store.HasKeywordSearch() {
+		s.sendError(w, store.ErrKeywordSearchUnavailable.Error(), http.StatusBadRequest)
+		return
+	}
 
 	var timing TimingInfo
+	var queryVec []float32
+	var vectorHits []ann.Hit
+	var vectorIDs, keywordIDs []uint64
 
-	// 1. Embed query
-	embedStart := time.Now()
-	queryVec, err := s.embedder.Embed(s.ctx, req.Query)
+	// Resolve the metadata filter to an allow-list up front (nil = no filter)
+	filterStart := time.Now()
+	allowed, err := s.store.FilterChunkIDs(s.ctx, req.Filter)
 	if err != nil {
-		s.sendError(w, fmt.Sprintf("Failed to embed query: %v", err), http.StatusInternalServerError)
+		s.sendError(w, fmt.Sprintf("Invalid filter: %v", err), http.StatusBadRequest)
 		return
 	}
-	timing.EmbedMs = time.Since(embedStart).Milliseconds()
+	timing.FilterMs = time.Since(filterStart).Milliseconds()
 
-	// 2. Exact nearest-neighbor search
-	searchStart := time.Now()
-	candidateIDs, err := s.annIndex.Search(queryVec, req.CandidateK)
-	if err != nil {
-		s.sendError(w, fmt.Sprintf("Search failed: %v", err), http.StatusInternalServerError)
-		return
+	if req.Mode != modeKeyword {
+		// 1. Embed query
+		embedStart := time.Now()
+		queryVec, err = s.embedder.EmbedQuery(s.ctx, req.Query)
+		if err != nil {
+			s.sendError(w, fmt.Sprintf("Failed to embed query: %v", err), http.StatusInternalServerError)
+			return
+		}
+		timing.EmbedMs = time.Since(embedStart).Milliseconds()
+
+		// 2. Exact nearest-neighbor search
+		searchStart := time.Now()
+		vectorHits, err = s.searchAllowed(queryVec, req.CandidateK, allowed)
+		if err != nil {
+			s.sendError(w, fmt.Sprintf("Search failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		vectorIDs = make([]uint64, len(vectorHits))
+		for i, h := range vectorHits {
+			vectorIDs[i] = h.ID
+		}
+		timing.SearchMs = time.Since(searchStart).Milliseconds()
+	}
+
+	if req.Mode != modeVector {
+		// 2b. BM25 keyword search
+		keywordStart := time.Now()
+		hits, err := s.store.KeywordSearch(s.ctx, req.Query, req.CandidateK, req.Filter)
+		if err != nil {
+			s.sendError(w, fmt.Sprintf("Keyword search failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		keywordIDs = make([]uint64, len(hits))
+		for i, h := range hits {
+			keywordIDs[i] = h.ID
+		}
+		timing.KeywordMs = time.Since(keywordStart).Milliseconds()
+	}
+
+	candidateIDs := vectorIDs
+	var fused []rank.Fused
+	if req.Mode != modeVector {
+		fused = rank.FuseRRF(
+			[][]uint64{vectorIDs, keywordIDs},
+			[]float32{req.VectorWeight, req.KeywordWeight},
+			req.RRFK,
+		)
+		if len(fused) > req.CandidateK {
+			fused = fused[:req.CandidateK]
+		}
+		candidateIDs = make([]uint64, len(fused))
+		for i, f := range fused {
+			candidateIDs[i] = f.ID
+		}
 	}
-	timing.SearchMs = time.Since(searchStart).Milliseconds()
 
 	if len(candidateIDs) == 0 {
 		s.sendResponse(w, &SearchResponse{
@@ -212,18 +385,37 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 3. Fetch chunks
+	// 3. Fetch chunk metadata. Both rerankers work from the scores already
+	// in hand, so the embedding blobs are never loaded.
 	fetchStart := time.Now()
-	chunks, err := s.store.GetChunksByIDs(s.ctx, candidateIDs)
+	chunks, err := s.store.GetChunkMetaByIDs(s.ctx, candidateIDs)
 	if err != nil {
 		s.sendError(w, fmt.Sprintf("Failed to fetch chunks: %v", err), http.StatusInternalServerError)
 		return
 	}
 	timing.FetchMs = time.Since(fetchStart).Milliseconds()
 
-	// 4. Rerank
+	// 4. Rerank. The graph boost can lift candidates from below the top N,
+	// so it sees them all.
 	rerankStart := time.Now()
-	results := rank.RerankCosine(queryVec, chunks, req.TopN)
+	topN := req.TopN
+	if req.GraphWeight > 0 {
+		topN = len(chunks)
+	}
+	var results []rank.Result
+	if req.Mode == modeVector {
+		results = rank.RerankHits(vectorHits, chunks, topN)
+	} else {
+		results = rank.RerankFused(fused, chunks, topN)
+	}
+	if req.GraphWeight > 0 {
+		neighbors, err := s.store.GetNoteNeighbors(s.ctx, rank.SeedNotes(results, req.GraphSeeds))
+		if err != nil {
+			s.sendError(w, fmt.Sprintf("Failed to load links: %v", err), http.StatusInternalServerError)
+			return
+		}
+		results = rank.GraphRerank(results, neighbors, req.GraphWeight, req.GraphSeeds, req.TopN)
+	}
 	timing.RerankMs = time.Since(rerankStart).Milliseconds()
 
 	timing.TotalMs = time.Since(startTime).Milliseconds()
@@ -242,6 +434,8 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 			Content:        r.Chunk.Content,
 			CategoryWeight: r.Chunk.CategoryWeight,
 			Tags:           r.Chunk.Tags,
+			ChunkTags:      r.Chunk.ChunkTags,
+			Explain:        r.Explain,
 		}
 	}
 
@@ -250,14 +444,30 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 		Timing:  timing,
 	})
 
-	log.Printf("✓ Search: \"%s\" → %d results in %dms (embed:%dms, search:%dms, fetch:%dms, rerank:%dms)",
-		req.Query, len(items), timing.TotalMs, timing.EmbedMs, timing.SearchMs, timing.FetchMs, timing.RerankMs)
+	log.Printf("✓ Search [%s]: \"%s\" → %d results in %dms (filter:%dms, embed:%dms, search:%dms, keyword:%dms, fetch:%dms, rerank:%dms)",
+		req.Mode, req.Query, len(items), timing.TotalMs, timing.FilterMs, timing.EmbedMs, timing.SearchMs, timing.KeywordMs, timing.FetchMs, timing.RerankMs)
+}
+
+// searchAllowed returns the k nearest neighbors, with their similarities,
+// whose IDs are in allowed (nil allows everything). The filter is applied
+// inside the index scan, so a filtered query still gets a full candidate set.
+func (s *Server) searchAllowed(queryVec []float32, k int, allowed map[uint64]struct{}) ([]ann.Hit, error) {
+	if allowed == nil {
+		return s.index().SearchWithScores(queryVec, k, nil)
+	}
+	if len(allowed) == 0 {
+		return nil, nil
+	}
+	return s.index().SearchWithScores(queryVec, k, func(id uint64) bool {
+		_, ok := allowed[id]
+		return ok
+	})
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":      "ok",
-		"index_size":  s.annIndex.Size(),
+		"index_size":  s.index().Size(),
 		"server_time": time.Now().Unix(),
 	})
 }
@@ -265,13 +475,99 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 	activeCount, _ := s.store.GetActiveChunkCount(s.ctx)
 
+	s.mu.RLock()
+	lastSeq := s.lastSeq
+	s.mu.RUnlock()
+
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"index_vectors": s.annIndex.Size(),
+		"index_vectors": s.index().Size(),
 		"active_chunks": activeCount,
+		"change_seq":    lastSeq,
+		"quantization":  s.annOpts.Quantization.String(),
 		"db_path":       *dbPath,
 	})
 }
 
+// handleLinks answers GET /links?path=... with a note's outgoing links,
+// unresolved links and backlinks. path is a stored note path or anything
+// [[path]] would resolve to (e.g. a note name).
+func (s *Server) handleLinks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sendLinks := func(code int, resp *LinksResponse) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(resp)
+	}
+
+	name := r.URL.Query().Get("path")
+	if name == "" {
+		sendLinks(http.StatusBadRequest, &LinksResponse{Error: "Missing path parameter"})
+		return
+	}
+	notePath, err := s.store.ResolveNote(r.Context(), name)
+	if err != nil {
+		sendLinks(http.StatusInternalServerError, &LinksResponse{Error: fmt.Sprintf("Resolve note: %v", err)})
+		return
+	}
+	if notePath == "" {
+		sendLinks(http.StatusNotFound, &LinksResponse{Error: fmt.Sprintf("No indexed note matches %q", name)})
+		return
+	}
+
+	outgoing, err := s.store.GetLinksFrom(r.Context(), notePath)
+	if err != nil {
+		sendLinks(http.StatusInternalServerError, &LinksResponse{Error: fmt.Sprintf("Read links: %v", err)})
+		return
+	}
+	backlinks, err := s.store.GetBacklinks(r.Context(), notePath)
+	if err != nil {
+		sendLinks(http.StatusInternalServerError, &LinksResponse{Error: fmt.Sprintf("Read backlinks: %v", err)})
+		return
+	}
+
+	resp := &LinksResponse{
+		Path:       notePath,
+		Outgoing:   []LinkItem{},
+		Unresolved: []LinkItem{},
+		Backlinks:  make([]LinkItem, len(backlinks)),
+	}
+	for _, l := range outgoing {
+		if l.ResolvedPath == "" {
+			resp.Unresolved = append(resp.Unresolved, linkItem(l))
+		} else {
+			resp.Outgoing = append(resp.Outgoing, linkItem(l))
+		}
+	}
+	for i, l := range backlinks {
+		resp.Backlinks[i] = linkItem(l)
+	}
+	sendLinks(http.StatusOK, resp)
+}
+
+func linkItem(l store.Link) LinkItem {
+	item := LinkItem{
+		SourcePath: l.SourcePath,
+		Line:       l.Line,
+		LinkType:   l.LinkType,
+		Target:     l.Target,
+		Heading:    l.Heading,
+		Alias:      l.Alias,
+		TargetPath: l.ResolvedPath,
+	}
+	if l.SourceChunkID != 0 {
+		item.Chunk = &LinkChunk{
+			HeadingPath: l.SourceHeadingPath,
+			StartLine:   l.SourceStartLine,
+			EndLine:     l.SourceEndLine,
+		}
+	}
+	return item
+}
+
 func (s *Server) sendResponse(w http.ResponseWriter, resp *SearchResponse) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
@@ -285,42 +581,128 @@ func (s *Server) sendError(w http.ResponseWriter, msg string, code int) {
 	})
 }
 
-func loadIndex(ctx context.Context, st *store.SQLite, annIndex ann.Index) error {
-	rows, err := st.StreamActiveEmbeddings(ctx)
+// index returns the current search index
+func (s *Server) index() ann.Index {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.annIndex
+}
+
+// reload builds a fresh index — from the vector snapshot when it is
+// usable, otherwise from SQLite — and swaps it in. The loaded index is
+// current to the returned change seq; anything committed later is picked
+// up by the next sync, and replay is idempotent (Replace/Remove).
+func (s *Server) reload() error {
+	if err := s.loadPrefixes(); err != nil {
+		return err
+	}
+	fresh, seq, err := snapshot.Load(s.ctx, s.store, s.snapshotPath, s.dim, s.model, s.annOpts)
 	if err != nil {
-		return fmt.Errorf("stream embeddings: %w", err)
+		return err
 	}
-	defer rows.Close()
+	log.Printf("✓ Loaded %d vectors into search index", fresh.Size())
 
-	count := 0
-	lastLog := time.Now()
+	s.mu.Lock()
+	old := s.annIndex
+	s.annIndex = fresh
+	s.lastSeq = seq
+	s.mu.Unlock()
 
-	for rows.Next() {
-		var id uint64
-		var vecBlob []byte
-		if err := rows.Scan(&id, &vecBlob); err != nil {
-			return fmt.Errorf("scan row: %w", err)
-		}
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
 
-		vec, err := store.BytesToFloat32(vecBlob)
-		if err != nil {
-			return fmt.Errorf("decode vec: %w", err)
+// syncLoop polls the change log until the server shuts down
+func (s *Server) syncLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.syncOnce(); err != nil {
+				log.Printf("⚠️  Index sync failed: %v", err)
+			}
 		}
+	}
+}
 
-		// Skip unloadable rows (e.g. zero-norm vectors in a pre-2026-07-23
-		// database) instead of refusing to start; log so they get noticed.
-		if err := annIndex.Add(id, vec); err != nil {
-			log.Printf("   Skipping chunk %d: %v", id, err)
-			continue
-		}
-		count++
+// syncOnce applies every change recorded since the last sync: new chunks
+// are upserted and deactivated chunks removed, in log order. If the server
+// fell so far behind that obsidx-gc pruned changes it had not applied, it
+// reloads the index instead.
+func (s *Server) syncOnce() error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	s.mu.RLock()
+	lastSeq, idx := s.lastSeq, s.annIndex
+	s.mu.RUnlock()
+
+	stats, err := snapshot.Replay(s.ctx, s.store, idx, lastSeq)
+	if errors.Is(err, store.ErrChangesPruned) {
+		log.Printf("⚠️  %v; reloading index", err)
+		return s.reload()
+	}
 
-		if count%1000 == 0 || time.Since(lastLog) > 2*time.Second {
-			log.Printf("   Loading vectors: %d...", count)
-			lastLog = time.Now()
-		}
+	// Advance past whatever was applied, even on error; replay is
+	// idempotent so a retry from here is safe.
+	s.mu.Lock()
+	s.lastSeq = stats.LastSeq
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if stats.Added > 0 || stats.Removed > 0 {
+		log.Printf("🔄 Synced index: +%d / -%d chunks (index size: %d)", stats.Added, stats.Removed, idx.Size())
 	}
+	// The indexer records new prefixes once it has re-embedded the vault
+	return s.loadPrefixes()
+}
 
-	log.Printf("✓ Loaded %d vectors into search index", count)
+// loadPrefixes embeds queries behind the query prefix the indexer recorded
+// with the index, so they match however its chunks were embedded. An index
+// built before prefixes were recorded was embedded without any.
+func (s *Server) loadPrefixes() error {
+	raw, err := s.store.GetIndexMeta(s.ctx, store.EmbedPrefixesKey)
+	if err != nil {
+		return fmt.Errorf("read embedding prefixes: %w", err)
+	}
+	var prefixes embed.Prefixes
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &prefixes); err != nil {
+			return fmt.Errorf("decode %s %q: %w", store.EmbedPrefixesKey, raw, err)
+		}
+	}
+	if s.queryPrefix != "auto" {
+		prefixes.Query = s.queryPrefix
+	}
+	if prefixes == s.embedder.Prefixes() {
+		return nil
+	}
+	s.embedder.SetPrefixes(prefixes)
+	if raw == "" {
+		log.Printf("⚠️  Index built without task prefixes; embedding queries as is until obsidx-indexer re-embeds the vault")
+	}
+	log.Printf("🔤 Query prefix: %q", prefixes.Query)
 	return nil
 }
+
+// saveSnapshot writes the index to the snapshot file so the next start
+// can skip streaming from SQLite. It holds syncMu so the saved vectors and
+// seq agree.
+func (s *Server) saveSnapshot() error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	s.mu.RLock()
+	idx, seq := s.annIndex, s.lastSeq
+	s.mu.RUnlock()
+
+	return snapshot.Save(idx, s.snapshotPath, s.model, seq)
+}
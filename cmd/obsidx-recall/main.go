@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"strings"
@@ -19,12 +20,44 @@ var (
 	candidateK = flag.Int("candidates", 200, "Number of candidates to retrieve")
 	jsonOutput = flag.Bool("json", false, "Output as JSON")
 	verbose    = flag.Bool("verbose", true, "Show timing information")
+	mode       = flag.String("mode", "vector", "Retrieval mode: vector, keyword or hybrid")
+	graphW     = flag.Float64("graph-weight", 0, "Boost results linked to or from the top notes by up to this much (0 = off, try 0.05)")
+	backlinks  = flag.String("backlinks", "", "Show the links to and from this note (path or name) instead of searching")
+
+	// Metadata filters (comma-separated lists)
+	tagsAny     = flag.String("tags", "", "Only notes with any of these tags")
+	tagsAll     = flag.String("all-tags", "", "Only notes with all of these tags")
+	tagsNone    = flag.String("exclude-tags", "", "Skip notes with any of these tags (e.g. archive)")
+	scopes      = flag.String("scope", "", "Only notes with one of these scopes")
+	statuses    = flag.String("status", "", "Only notes with one of these statuses")
+	noteTypes   = flag.String("type", "", "Only notes of these types")
+	pathPrefix  = flag.String("path", "", "Only notes under this path prefix (e.g. projects/alpha)")
+	pathGlob    = flag.String("glob", "", "Only notes whose path matches this glob (e.g. '*/ADR-*.md')")
+	modifiedMin = flag.String("since", "", "Only notes modified on or after this date (YYYY-MM-DD)")
+	modifiedMax = flag.String("until", "", "Only notes modified on or before this date (YYYY-MM-DD)")
 )
 
 type SearchRequest struct {
-	Query      string `json:"query"`
-	TopN       int    `json:"top_n"`
-	CandidateK int    `json:"candidate_k"`
+	Query       string        `json:"query"`
+	TopN        int           `json:"top_n"`
+	CandidateK  int           `json:"candidate_k"`
+	Mode        string        `json:"mode,omitempty"`
+	GraphWeight float32       `json:"graph_weight,omitempty"`
+	Filter      *SearchFilter `json:"filter,omitempty"`
+}
+
+// SearchFilter mirrors store.ChunkFilter on the wire
+type SearchFilter struct {
+	TagsAny        []string `json:"tags_any,omitempty"`
+	TagsAll        []string `json:"tags_all,omitempty"`
+	TagsNone       []string `json:"tags_none,omitempty"`
+	Scope          []string `json:"scope,omitempty"`
+	Status         []string `json:"status,omitempty"`
+	NoteType       []string `json:"note_type,omitempty"`
+	PathPrefix     string   `json:"path_prefix,omitempty"`
+	PathGlob       string   `json:"path_glob,omitempty"`
+	ModifiedAfter  string   `json:"modified_after,omitempty"`
+	ModifiedBefore string   `json:"modified_before,omitempty"`
 }
 
 type SearchResponse struct {
@@ -44,64 +77,80 @@ type ResultItem struct {
 	Content        string   `json:"content"`
 	CategoryWeight float32  `json:"category_weight"`
 	Tags           []string `json:"tags"`
+	ChunkTags      []string `json:"chunk_tags,omitempty"` // inline tags in this chunk
+	Explain        Explain  `json:"explain"`
+}
+
+// Explain mirrors rank.Explanation: score = base × category_weight + graph
+type Explain struct {
+	Base           float32  `json:"base"`
+	CategoryWeight float32  `json:"category_weight"`
+	Graph          float32  `json:"graph,omitempty"`
+	LinkedNotes    []string `json:"linked_notes,omitempty"`
+}
+
+// LinksResponse mirrors the server's GET /links response
+type LinksResponse struct {
+	Path       string     `json:"path"`
+	Outgoing   []LinkItem `json:"outgoing"`
+	Unresolved []LinkItem `json:"unresolved"`
+	Backlinks  []LinkItem `json:"backlinks"`
+	Error      string     `json:"error,omitempty"`
+}
+
+type LinkItem struct {
+	SourcePath string     `json:"source_path"`
+	Line       int        `json:"line"`
+	LinkType   string     `json:"link_type"`
+	Target     string     `json:"target"`
+	Heading    string     `json:"heading,omitempty"`
+	Alias      string     `json:"alias,omitempty"`
+	TargetPath string     `json:"target_path,omitempty"`
+	Chunk      *LinkChunk `json:"chunk,omitempty"`
+}
+
+type LinkChunk struct {
+	HeadingPath string `json:"heading_path"`
+	StartLine   int    `json:"start_line"`
+	EndLine     int    `json:"end_line"`
 }
 
 type TimingInfo struct {
-	EmbedMs  int64 `json:"embed_ms"`
-	SearchMs int64 `json:"search_ms"`
-	FetchMs  int64 `json:"fetch_ms"`
-	RerankMs int64 `json:"rerank_ms"`
-	TotalMs  int64 `json:"total_ms"`
+	EmbedMs   int64 `json:"embed_ms"`
+	SearchMs  int64 `json:"search_ms"`
+	KeywordMs int64 `json:"keyword_ms"`
+	FetchMs   int64 `json:"fetch_ms"`
+	RerankMs  int64 `json:"rerank_ms"`
+	TotalMs   int64 `json:"total_ms"`
 }
 
 func main() {
 	flag.Parse()
 
-	if flag.NArg() < 1 {
+	if flag.NArg() < 1 && *backlinks == "" {
 		fmt.Fprintf(os.Stderr, "Usage: %s [options] <query>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] --backlinks <note>\n", os.Args[0])
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	query := strings.Join(flag.Args(), " ")
-
-	// Check if server is running
-	if !isServerRunning() {
-		fmt.Fprintf(os.Stderr, "❌ Recall server is not running\n\n")
-		fmt.Fprintf(os.Stderr, "Start the server with:\n")
-		fmt.Fprintf(os.Stderr, "  ./start-daemon.sh\n\n")
-		fmt.Fprintf(os.Stderr, "Or start it now automatically? [Y/n] ")
-
-		var response string
-		fmt.Scanln(&response)
-
-		if response == "" || strings.ToLower(response) == "y" {
-			fmt.Fprintf(os.Stderr, "Starting server...\n")
-			cmd := exec.Command("./start-daemon.sh")
-			cmd.Stdout = os.Stderr
-			cmd.Stderr = os.Stderr
-			if err := cmd.Run(); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to start server: %v\n", err)
-				os.Exit(1)
-			}
-			// Give server time to start
-			time.Sleep(2 * time.Second)
+	ensureServer()
 
-			// Check again
-			if !isServerRunning() {
-				fmt.Fprintf(os.Stderr, "Server failed to start. Check logs at .obsidian-index/recall-server.log\n")
-				os.Exit(1)
-			}
-		} else {
-			os.Exit(1)
-		}
+	if *backlinks != "" {
+		showLinks(*backlinks)
+		return
 	}
 
+	query := strings.Join(flag.Args(), " ")
+
 	// Build request
 	req := SearchRequest{
-		Query:      query,
-		TopN:       *topN,
-		CandidateK: *candidateK,
+		Query:       query,
+		TopN:        *topN,
+		CandidateK:  *candidateK,
+		Mode:        *mode,
+		GraphWeight: float32(*graphW),
+		Filter:      buildFilter(),
 	}
 
 	reqBody, err := json.Marshal(req)
@@ -145,6 +194,142 @@ func main() {
 	}
 }
 
+// ensureServer offers to start the recall server if it is not running,
+// and exits if it cannot be reached
+func ensureServer() {
+	if isServerRunning() {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "❌ Recall server is not running\n\n")
+	fmt.Fprintf(os.Stderr, "Start the server with:\n")
+	fmt.Fprintf(os.Stderr, "  ./start-daemon.sh\n\n")
+	fmt.Fprintf(os.Stderr, "Or start it now automatically? [Y/n] ")
+
+	var response string
+	fmt.Scanln(&response)
+
+	if response == "" || strings.ToLower(response) == "y" {
+		fmt.Fprintf(os.Stderr, "Starting server...\n")
+		cmd := exec.Command("./start-daemon.sh")
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start server: %v\n", err)
+			os.Exit(1)
+		}
+		// Give server time to start
+		time.Sleep(2 * time.Second)
+
+		// Check again
+		if !isServerRunning() {
+			fmt.Fprintf(os.Stderr, "Server failed to start. Check logs at .obsidian-index/recall-server.log\n")
+			os.Exit(1)
+		}
+	} else {
+		os.Exit(1)
+	}
+}
+
+// showLinks prints the links to and from a note
+func showLinks(note string) {
+	resp, err := http.Get(*serverURL + "/links?path=" + url.QueryEscape(note))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to server: %v\n", err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+
+	var links LinksResponse
+	if err := json.NewDecoder(resp.Body).Decode(&links); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing response: %v\n", err)
+		os.Exit(1)
+	}
+	if links.Error != "" {
+		fmt.Fprintf(os.Stderr, "Server error: %s\n", links.Error)
+		os.Exit(1)
+	}
+
+	if *jsonOutput {
+		output, _ := json.MarshalIndent(links, "", "  ")
+		fmt.Println(string(output))
+		return
+	}
+
+	fmt.Printf("🔗 Links for %s\n", links.Path)
+
+	fmt.Printf("\nBacklinks (%d):\n", len(links.Backlinks))
+	for _, l := range links.Backlinks {
+		fmt.Printf("  ← %s:%d  %s", l.SourcePath, l.Line, formatLink(l))
+		if l.Chunk != nil && l.Chunk.HeadingPath != "" {
+			fmt.Printf("  § %s (lines %d-%d)", l.Chunk.HeadingPath, l.Chunk.StartLine, l.Chunk.EndLine)
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("\nOutgoing (%d):\n", len(links.Outgoing))
+	for _, l := range links.Outgoing {
+		fmt.Printf("  → %s  %s (line %d)\n", l.TargetPath, formatLink(l), l.Line)
+	}
+
+	if len(links.Unresolved) > 0 {
+		fmt.Printf("\nUnresolved (%d):\n", len(links.Unresolved))
+		for _, l := range links.Unresolved {
+			fmt.Printf("  ✗ %s (line %d)\n", formatLink(l), l.Line)
+		}
+	}
+}
+
+// formatLink renders a link roughly as it was written
+func formatLink(l LinkItem) string {
+	target := l.Target
+	if l.Heading != "" {
+		target += "#" + l.Heading
+	}
+	switch l.LinkType {
+	case "markdown":
+		return fmt.Sprintf("[%s](%s)", l.Alias, target)
+	case "embed":
+		return "![[" + target + "]]"
+	}
+	if l.Alias != "" {
+		target += "|" + l.Alias
+	}
+	return "[[" + target + "]]"
+}
+
+// buildFilter assembles the metadata filter from flags (nil if none set)
+func buildFilter() *SearchFilter {
+	f := &SearchFilter{
+		TagsAny:        splitList(*tagsAny),
+		TagsAll:        splitList(*tagsAll),
+		TagsNone:       splitList(*tagsNone),
+		Scope:          splitList(*scopes),
+		Status:         splitList(*statuses),
+		NoteType:       splitList(*noteTypes),
+		PathPrefix:     *pathPrefix,
+		PathGlob:       *pathGlob,
+		ModifiedAfter:  *modifiedMin,
+		ModifiedBefore: *modifiedMax,
+	}
+	if len(f.TagsAny) == 0 && len(f.TagsAll) == 0 && len(f.TagsNone) == 0 &&
+		len(f.Scope) == 0 && len(f.Status) == 0 && len(f.NoteType) == 0 &&
+		f.PathPrefix == "" && f.PathGlob == "" && f.ModifiedAfter == "" && f.ModifiedBefore == "" {
+		return nil
+	}
+	return f
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
 func isServerRunning() bool {
 	client := &http.Client{
 		Timeout: 1 * time.Second,
@@ -162,8 +347,8 @@ func isServerRunning() bool {
 func printResults(query string, results []ResultItem, timing TimingInfo) {
 	if *verbose {
 		fmt.Printf("⚡ Fast search: \"%s\"\n", query)
-		fmt.Printf("⏱️  Total: %dms (embed:%dms, search:%dms, fetch:%dms, rerank:%dms)\n\n",
-			timing.TotalMs, timing.EmbedMs, timing.SearchMs, timing.FetchMs, timing.RerankMs)
+		fmt.Printf("⏱️  Total: %dms (embed:%dms, search:%dms, keyword:%dms, fetch:%dms, rerank:%dms)\n\n",
+			timing.TotalMs, timing.EmbedMs, timing.SearchMs, timing.KeywordMs, timing.FetchMs, timing.RerankMs)
 	}
 
 	fmt.Printf("Found %d results:\n\n", len(results))
@@ -171,6 +356,13 @@ func printResults(query string, results []ResultItem, timing TimingInfo) {
 	for i, r := range results {
 		fmt.Printf("─────────────────────────────────────────────────────────────\n")
 		fmt.Printf("[%d] Score: %.4f", i+1, r.Score)
+		if *verbose {
+			fmt.Printf(" (%.4f × %.2f", r.Explain.Base, r.Explain.CategoryWeight)
+			if r.Explain.Graph > 0 {
+				fmt.Printf(" + %.4f linked to %s", r.Explain.Graph, strings.Join(r.Explain.LinkedNotes, ", "))
+			}
+			fmt.Printf(")")
+		}
 
 		// Show tags
 		if len(r.Tags) > 0 {
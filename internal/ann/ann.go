@@ -6,15 +6,39 @@ package ann
 // embeddings; see BruteForce) so it can be swapped if needed. The index is
 // in-memory only, rebuilt from SQLite at startup.
 type Index interface {
-	// Add inserts a vector with given ID
+	// Add inserts a vector with given ID. Adding an ID that is already
+	// present is an error; use Replace to overwrite.
 	Add(id uint64, vec []float32) error
 
+	// Remove deletes the vector with given ID. Removing an absent ID is a
+	// no-op, so replaying a removal is safe.
+	Remove(id uint64) error
+
+	// Replace atomically inserts or overwrites the vector with given ID
+	Replace(id uint64, vec []float32) error
+
 	// Search returns the k nearest neighbor IDs for the query vector
 	Search(vec []float32, k int) ([]uint64, error)
 
+	// SearchFiltered is Search restricted to IDs for which accept returns
+	// true; rejected IDs never occupy a result slot. accept may be called
+	// concurrently and must be safe for that. A nil accept allows all IDs.
+	SearchFiltered(vec []float32, k int, accept func(id uint64) bool) ([]uint64, error)
+
+	// SearchWithScores is SearchFiltered that also returns each neighbor's
+	// cosine similarity to the query, best first, so callers can rank
+	// without re-fetching and re-scoring the vectors.
+	SearchWithScores(vec []float32, k int, accept func(id uint64) bool) ([]Hit, error)
+
 	// Close releases resources
 	Close() error
 
 	// Size returns the number of vectors in the index
 	Size() int
 }
+
+// Hit is a search result: a vector ID and its cosine similarity to the query
+type Hit struct {
+	ID  uint64
+	Sim float32
+}
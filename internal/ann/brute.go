@@ -16,25 +16,53 @@ import (
 // coder/hnsw graph shipped previously returned near-zero recall on this
 // vault's real embeddings even after duplicate-vector clusters were purged
 // (2026-07-23), while an exact scan is immune by construction.
+//
+// Removal is a swap-delete: the last slot moves into the hole, so storage
+// stays dense and a long-running watch process never scans dead vectors.
+// Slot order carries no meaning — Search ranks by a total order.
+//
+// With quantization enabled (see Options) the scan reads compact codes
+// instead of the float32 rows and only the best candidates are rescored
+// exactly, so returned similarities are always exact cosine.
 type BruteForce struct {
 	dim  int
+	opts Options
 	mu   sync.RWMutex
 	ids  []uint64
 	vecs [][]float32 // stored L2-normalized so search is a pure dot product
+	pos  map[uint64]int
+
+	// Scan codes, parallel to vecs; only the set matching opts is used
+	scale  []float32  // int8: per-dimension quantization range
+	codes8 [][]int8   // int8 codes
+	bits   [][]uint64 // binary: packed sign bits
+
+	// release unmaps snapshot-backed storage (see LoadBruteForce); nil
+	// for an index built in memory
+	release func() error
 }
 
 // NewBruteForce creates an exact-search index for vectors of the given dimension.
 func NewBruteForce(dim int) *BruteForce {
-	return &BruteForce{dim: dim}
+	return NewBruteForceWithOptions(dim, Options{})
 }
 
-// Add inserts a vector with the given ID. The vector is copied and normalized.
+// NewBruteForceWithOptions creates an index with the given storage options.
+func NewBruteForceWithOptions(dim int, opts Options) *BruteForce {
+	b := &BruteForce{dim: dim, opts: opts, pos: make(map[uint64]int)}
+	if opts.Quantization == QuantInt8 {
+		b.scale = make([]float32, dim)
+	}
+	return b
+}
+
+// normalize returns an L2-normalized copy of vec.
 // Zero-norm vectors are rejected: they are unembeddable junk (e.g. the
 // embedding of an empty string) and would rank at sim 0 above genuinely
 // anti-correlated results.
-func (b *BruteForce) Add(id uint64, vec []float32) error {
+func (b *BruteForce) normalize(id uint64, vec []float32) ([]float32, error) {
 	if len(vec) != b.dim {
-		return fmt.Errorf("vector dimension mismatch: got %d, expected %d", len(vec), b.dim)
+		return nil, fmt.Errorf("vector dimension mismatch: got %d, expected %d", len(vec), b.dim)
 	}
 	norm := float32(0)
 	for _, x := range vec {
@@ -42,23 +70,86 @@ func (b *BruteForce) Add(id uint64, vec []float32) error {
 	}
 	norm = float32(math.Sqrt(float64(norm)))
 	if norm == 0 {
-		return fmt.Errorf("zero-norm vector for id %d", id)
+		return nil, fmt.Errorf("zero-norm vector for id %d", id)
 	}
 	stored := make([]float32, len(vec))
 	for i, x := range vec {
 		stored[i] = x / norm
 	}
+	return stored, nil
+}
+
+// Add inserts a vector with the given ID. The vector is copied and normalized.
+func (b *BruteForce) Add(id uint64, vec []float32) error {
+	stored, err := b.normalize(id, vec)
+	if err != nil {
+		return err
+	}
 
 	b.mu.Lock()
 	defer b.mu.Unlock()
+	if _, exists := b.pos[id]; exists {
+		return fmt.Errorf("duplicate id %d", id)
+	}
+	b.appendSlot(id, stored)
+	return nil
+}
+
+// appendSlot stores a normalized vector in a new slot. Caller holds mu.
+func (b *BruteForce) appendSlot(id uint64, stored []float32) {
+	b.pos[id] = len(b.ids)
 	b.ids = append(b.ids, id)
 	b.vecs = append(b.vecs, stored)
+	b.appendCodes(stored)
+}
+
+// Replace inserts the vector, overwriting any existing vector with the same
+// ID in place. The old vector is never visible alongside the new one.
+func (b *BruteForce) Replace(id uint64, vec []float32) error {
+	stored, err := b.normalize(id, vec)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if i, exists := b.pos[id]; exists {
+		b.vecs[i] = stored
+		b.setCodes(i, stored)
+		return nil
+	}
+	b.appendSlot(id, stored)
+	return nil
+}
+
+// Remove deletes the vector with the given ID by moving the last slot into
+// its place. Removing an absent ID is a no-op.
+func (b *BruteForce) Remove(id uint64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i, exists := b.pos[id]
+	if !exists {
+		return nil
+	}
+	last := len(b.ids) - 1
+	if i != last {
+		b.ids[i] = b.ids[last]
+		b.vecs[i] = b.vecs[last]
+		b.pos[b.ids[i]] = i
+	}
+	b.vecs[last] = nil // release the vector
+	b.ids = b.ids[:last]
+	b.vecs = b.vecs[:last]
+	b.removeCodes(i, last)
+	delete(b.pos, id)
 	return nil
 }
 
 type scored struct {
-	id  uint64
-	sim float32
+	id   uint64
+	sim  float32
+	slot int // storage slot, for rescoring
 }
 
 // worse reports whether a ranks strictly below b under the total order
@@ -89,6 +180,30 @@ func (h *minHeap) Pop() interface{} {
 
 // Search returns the k exact nearest neighbors by cosine similarity.
 func (b *BruteForce) Search(vec []float32, k int) ([]uint64, error) {
+	return b.SearchFiltered(vec, k, nil)
+}
+
+// SearchFiltered returns the k exact nearest neighbors among the IDs
+// accepted by accept (nil accepts all). Rejected IDs are skipped inside
+// each worker's stripe scan, before they can take a heap slot, so a
+// selective filter still yields a full k results when enough IDs pass.
+func (b *BruteForce) SearchFiltered(vec []float32, k int, accept func(id uint64) bool) ([]uint64, error) {
+	hits, err := b.SearchWithScores(vec, k, accept)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint64, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ID
+	}
+	return ids, nil
+}
+
+// SearchWithScores is SearchFiltered returning the exact cosine similarity
+// computed during the scan alongside each ID. With quantization the scan
+// keeps k×Rescore candidates by approximate score, then rescores those
+// against the float32 rows; the similarities returned are exact.
+func (b *BruteForce) SearchWithScores(vec []float32, k int, accept func(id uint64) bool) ([]Hit, error) {
 	if len(vec) != b.dim {
 		return nil, fmt.Errorf("vector dimension mismatch: got %d, expected %d", len(vec), b.dim)
 	}
@@ -120,6 +235,36 @@ func (b *BruteForce) Search(vec []float32, k int) ([]uint64, error) {
 		q[i] = x / norm
 	}
 
+	var top []scored
+	if b.opts.Quantization == QuantNone {
+		top = b.scan(k, accept, func(i int) float32 { return dot(q, b.vecs[i]) })
+	} else {
+		m := k * b.opts.rescore()
+		if m > n {
+			m = n
+		}
+		top = b.scan(m, accept, b.quantScorer(q))
+		for i := range top {
+			top[i].sim = dot(q, b.vecs[top[i].slot])
+		}
+		sort.Slice(top, func(i, j int) bool { return worse(top[j], top[i]) })
+		if len(top) > k {
+			top = top[:k]
+		}
+	}
+
+	hits := make([]Hit, len(top))
+	for i, s := range top {
+		hits[i] = Hit{ID: s.id, Sim: s.sim}
+	}
+	return hits, nil
+}
+
+// scan scores every accepted slot and returns the best k, best first.
+// Caller holds mu for reading.
+func (b *BruteForce) scan(k int, accept func(id uint64) bool, score func(slot int) float32) []scored {
+	n := len(b.ids)
+
 	// Parallel scan: each worker keeps its own top-k heap over a stripe.
 	workers := runtime.GOMAXPROCS(0)
 	if workers > n {
@@ -142,12 +287,10 @@ func (b *BruteForce) Search(vec []float32, k int) ([]uint64, error) {
 			defer wg.Done()
 			h := make(minHeap, 0, k)
 			for i := start; i < end; i++ {
-				v := b.vecs[i]
-				var dot float32
-				for j := range q {
-					dot += q[j] * v[j]
+				if accept != nil && !accept(b.ids[i]) {
+					continue
 				}
-				cand := scored{b.ids[i], dot}
+				cand := scored{b.ids[i], score(i), i}
 				if len(h) < k {
 					heap.Push(&h, cand)
 				} else if worse(h[0], cand) {
@@ -169,15 +312,32 @@ func (b *BruteForce) Search(vec []float32, k int) ([]uint64, error) {
 	if len(all) > k {
 		all = all[:k]
 	}
-	ids := make([]uint64, len(all))
-	for i, s := range all {
-		ids[i] = s.id
+	return all
+}
+
+func dot(a, b []float32) float32 {
+	var d float32
+	for j := range a {
+		d += a[j] * b[j]
 	}
-	return ids, nil
+	return d
 }
 
-// Close releases resources (none held).
-func (b *BruteForce) Close() error { return nil }
+// Close releases resources. For a snapshot-backed index it waits for
+// in-flight searches and then unmaps the file; the index is empty after.
+func (b *BruteForce) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ids, b.vecs = nil, nil
+	b.codes8, b.bits = nil, nil
+	b.pos = make(map[uint64]int)
+	if b.release == nil {
+		return nil
+	}
+	err := b.release()
+	b.release = nil
+	return err
+}
 
 // Size returns the number of stored vectors.
 func (b *BruteForce) Size() int {
@@ -152,6 +152,11 @@ func TestBruteForceRejectsZeroNormQuery(t *testing.T) {
 
 // naiveTopK is an independent sequential implementation used as ground truth.
 func naiveTopK(ids []uint64, vecs [][]float32, q []float32, k int) []uint64 {
+	return naiveTopKFiltered(ids, vecs, q, k, nil)
+}
+
+// naiveTopKFiltered is naiveTopK over only the IDs accept allows.
+func naiveTopKFiltered(ids []uint64, vecs [][]float32, q []float32, k int, accept func(uint64) bool) []uint64 {
 	type sc struct {
 		id  uint64
 		sim float32
@@ -163,6 +168,9 @@ func naiveTopK(ids []uint64, vecs [][]float32, q []float32, k int) []uint64 {
 	}
 	qn = float32(math.Sqrt(float64(qn)))
 	for i := range ids {
+		if accept != nil && !accept(ids[i]) {
+			continue
+		}
 		var vn, dot float32
 		for j := range vecs[i] {
 			vn += vecs[i][j] * vecs[i][j]
@@ -232,6 +240,128 @@ func TestBruteForceMatchesSequentialScan(t *testing.T) {
 	}
 }
 
+// TestBruteForceFilteredMatchesSequentialScan pins SearchFiltered against a
+// filtered sequential scan, with the same planted cross-stripe ties as the
+// unfiltered test. Filters of varying selectivity must still return a full
+// k whenever k IDs pass, in (sim desc, id asc) order.
+func TestBruteForceFilteredMatchesSequentialScan(t *testing.T) {
+	const dim = 32
+	const n = 5000
+	rng := rand.New(rand.NewSource(9))
+
+	idx := NewBruteForce(dim)
+	var ids []uint64
+	var vecs [][]float32
+	dup := randVec(rng, dim)
+	for i := 0; i < n; i++ {
+		var v []float32
+		if i%17 == 0 {
+			v = append([]float32(nil), dup...)
+		} else {
+			v = randVec(rng, dim)
+		}
+		id := uint64(i * 3)
+		if err := idx.Add(id, v); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+		ids = append(ids, id)
+		vecs = append(vecs, v)
+	}
+
+	filters := []struct {
+		name   string
+		accept func(uint64) bool
+	}{
+		{"every other id", func(id uint64) bool { return id%2 == 0 }},
+		{"1 in 50", func(id uint64) bool { return id%50 == 0 }},
+		{"only planted ties", func(id uint64) bool { return (id/3)%17 == 0 }},
+		{"fewer than k pass", func(id uint64) bool { return id < 30 }},
+		{"nothing passes", func(uint64) bool { return false }},
+	}
+	for _, f := range filters {
+		t.Run(f.name, func(t *testing.T) {
+			for trial := 0; trial < 3; trial++ {
+				q := randVec(rng, dim)
+				if trial == 0 {
+					q = append([]float32(nil), dup...) // query the tie cluster directly
+				}
+				got, err := idx.SearchFiltered(q, 25, f.accept)
+				if err != nil {
+					t.Fatalf("search: %v", err)
+				}
+				want := naiveTopKFiltered(ids, vecs, q, 25, f.accept)
+				if len(got) != len(want) {
+					t.Fatalf("trial %d: got %d results, want %d", trial, len(got), len(want))
+				}
+				for i := range got {
+					if got[i] != want[i] {
+						t.Errorf("trial %d rank %d: got id %d, want id %d", trial, i, got[i], want[i])
+					}
+				}
+			}
+		})
+	}
+
+	// A nil filter is plain Search.
+	q := randVec(rng, dim)
+	got, _ := idx.SearchFiltered(q, 10, nil)
+	want, _ := idx.Search(q, 10)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("nil filter rank %d: got %d, want %d", i, got[i], want[i])
+		}
+	}
+}
+
+// TestBruteForceSearchWithScores checks the returned similarities are the
+// exact cosine of each hit, best first, and agree with Search's IDs.
+func TestBruteForceSearchWithScores(t *testing.T) {
+	const dim = 24
+	rng := rand.New(rand.NewSource(11))
+	idx := NewBruteForce(dim)
+	vecs := make(map[uint64][]float32)
+	for i := 0; i < 500; i++ {
+		v := randVec(rng, dim)
+		id := uint64(i + 1)
+		if err := idx.Add(id, v); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+		vecs[id] = v
+	}
+
+	q := randVec(rng, dim)
+	hits, err := idx.SearchWithScores(q, 20, nil)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	ids, _ := idx.Search(q, 20)
+	if len(hits) != len(ids) {
+		t.Fatalf("got %d hits, want %d", len(hits), len(ids))
+	}
+	for i, h := range hits {
+		if h.ID != ids[i] {
+			t.Errorf("rank %d: got id %d, Search has %d", i, h.ID, ids[i])
+		}
+		if i > 0 && h.Sim > hits[i-1].Sim {
+			t.Errorf("rank %d: sim %f above previous %f", i, h.Sim, hits[i-1].Sim)
+		}
+		want := cosine(q, vecs[h.ID])
+		if math.Abs(float64(h.Sim-want)) > 1e-5 {
+			t.Errorf("id %d: got sim %f, want %f", h.ID, h.Sim, want)
+		}
+	}
+}
+
+func cosine(a, b []float32) float32 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	return float32(dot / (math.Sqrt(na) * math.Sqrt(nb)))
+}
+
 // TestBruteForceConcurrentAddSearch exercises Add and Search under real
 // contention so `go test -race` observes the locking, converting the
 // by-inspection safety argument into evidence.
@@ -278,3 +408,118 @@ func TestBruteForceConcurrentAddSearch(t *testing.T) {
 		t.Errorf("Size() = %d, want 600", idx.Size())
 	}
 }
+
+func TestBruteForceRejectsDuplicateAdd(t *testing.T) {
+	idx := NewBruteForce(4)
+	if err := idx.Add(1, []float32{1, 0, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Add(1, []float32{0, 1, 0, 0}); err == nil {
+		t.Error("expected error adding duplicate id")
+	}
+	if idx.Size() != 1 {
+		t.Errorf("Size() = %d, want 1", idx.Size())
+	}
+}
+
+// TestBruteForceRemoveMatchesSequentialScan removes a third of the vectors
+// (including the last slot and slots that get swapped into) and checks the
+// survivors still rank exactly like an independent scan over them.
+func TestBruteForceRemoveMatchesSequentialScan(t *testing.T) {
+	const dim = 32
+	const n = 3000
+	rng := rand.New(rand.NewSource(5))
+
+	idx := NewBruteForce(dim)
+	all := make(map[uint64][]float32, n)
+	for i := 0; i < n; i++ {
+		v := randVec(rng, dim)
+		if err := idx.Add(uint64(i), v); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+		all[uint64(i)] = v
+	}
+
+	for i := 0; i < n; i += 3 {
+		if err := idx.Remove(uint64(i)); err != nil {
+			t.Fatalf("remove %d: %v", i, err)
+		}
+		delete(all, uint64(i))
+	}
+	if err := idx.Remove(uint64(n - 1)); err != nil {
+		t.Fatalf("remove last: %v", err)
+	}
+	delete(all, uint64(n-1))
+	// Removing again (or an ID never added) is a no-op.
+	if err := idx.Remove(0); err != nil {
+		t.Fatalf("repeat remove: %v", err)
+	}
+	if err := idx.Remove(123_456); err != nil {
+		t.Fatalf("remove absent: %v", err)
+	}
+	if idx.Size() != len(all) {
+		t.Fatalf("Size() = %d, want %d", idx.Size(), len(all))
+	}
+
+	var ids []uint64
+	var vecs [][]float32
+	for id, v := range all {
+		ids = append(ids, id)
+		vecs = append(vecs, v)
+	}
+	for trial := 0; trial < 5; trial++ {
+		q := randVec(rng, dim)
+		got, err := idx.Search(q, 20)
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		want := naiveTopK(ids, vecs, q, 20)
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("trial %d rank %d: got id %d, want id %d", trial, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestBruteForceReplace(t *testing.T) {
+	idx := NewBruteForce(4)
+	if err := idx.Add(1, []float32{1, 0, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Add(2, []float32{0, 1, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Overwrite id 1 so it now points where id 2 does not.
+	if err := idx.Replace(1, []float32{0, 0, 1, 0}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	if idx.Size() != 2 {
+		t.Errorf("Size() after replace = %d, want 2", idx.Size())
+	}
+	ids, err := idx.Search([]float32{0, 0, 1, 0}, 1)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != 1 {
+		t.Errorf("expected replaced id 1 first, got %v", ids)
+	}
+
+	// Replace of an absent id inserts it.
+	if err := idx.Replace(3, []float32{0, 0, 0, 1}); err != nil {
+		t.Fatalf("replace absent: %v", err)
+	}
+	if idx.Size() != 3 {
+		t.Errorf("Size() after insert-by-replace = %d, want 3", idx.Size())
+	}
+
+	// Invalid vectors leave the existing entry untouched.
+	if err := idx.Replace(1, []float32{0, 0, 0, 0}); err == nil {
+		t.Error("expected error replacing with zero-norm vector")
+	}
+	ids, _ = idx.Search([]float32{0, 0, 1, 0}, 1)
+	if len(ids) != 1 || ids[0] != 1 {
+		t.Errorf("failed replace clobbered id 1; got %v", ids)
+	}
+}
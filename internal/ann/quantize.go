@@ -0,0 +1,221 @@
+package ann
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Quantization selects the representation BruteForce scans.
+//
+// The float32 rows are always kept: candidates are rescored against them
+// and snapshots are written from them. What quantization shrinks is the
+// data the scan streams through memory — the bottleneck at vault scale —
+// by 4× (int8) or 32× (binary). When the index is loaded from a
+// memory-mapped snapshot the float32 rows are clean file-backed pages, so
+// the OS can evict the ones rescoring never touches.
+type Quantization int
+
+const (
+	// QuantNone scans float32 rows; results are exact by construction
+	QuantNone Quantization = iota
+	// QuantInt8 scans one byte per dimension, scaled per dimension
+	QuantInt8
+	// QuantBinary scans one sign bit per dimension by Hamming distance
+	QuantBinary
+)
+
+// Options configures BruteForce storage
+type Options struct {
+	Quantization Quantization
+
+	// Rescore is how many quantized candidates are kept per requested
+	// result for exact float32 rescoring. Zero uses a per-mode default.
+	Rescore int
+}
+
+// Default rescoring factors: int8 scores are close to exact, so few extra
+// candidates are needed; sign bits are much coarser.
+const (
+	defaultRescoreInt8   = 4
+	defaultRescoreBinary = 16
+)
+
+func (o Options) rescore() int {
+	if o.Rescore > 0 {
+		return o.Rescore
+	}
+	if o.Quantization == QuantBinary {
+		return defaultRescoreBinary
+	}
+	return defaultRescoreInt8
+}
+
+// ParseQuantization parses "none", "int8" or "binary" ("" means none)
+func ParseQuantization(s string) (Quantization, error) {
+	switch s {
+	case "", "none":
+		return QuantNone, nil
+	case "int8":
+		return QuantInt8, nil
+	case "binary":
+		return QuantBinary, nil
+	}
+	return QuantNone, fmt.Errorf("unknown quantization %q (want none, int8 or binary)", s)
+}
+
+func (q Quantization) String() string {
+	switch q {
+	case QuantInt8:
+		return "int8"
+	case QuantBinary:
+		return "binary"
+	}
+	return "none"
+}
+
+// scaleHeadroom is how far past the largest value seen a dimension's int8
+// range is widened, so that bulk loading settles after a few
+// requantizations instead of one per new extreme.
+const scaleHeadroom = 1.25
+
+// quantScorer returns the approximate scan score for a slot against the
+// normalized query q. Caller holds mu for reading.
+func (b *BruteForce) quantScorer(q []float32) func(slot int) float32 {
+	switch b.opts.Quantization {
+	case QuantInt8:
+		// Fold the per-dimension scale into the query once:
+		// Σ q_d·x_d ≈ Σ (q_d·scale_d/127)·code_d
+		qs := make([]float32, len(q))
+		for d := range q {
+			qs[d] = q[d] * b.scale[d] / 127
+		}
+		return func(slot int) float32 {
+			c := b.codes8[slot]
+			var s float32
+			for d := range qs {
+				s += qs[d] * float32(c[d])
+			}
+			return s
+		}
+	case QuantBinary:
+		qb := signBits(q)
+		dim := float32(b.dim)
+		return func(slot int) float32 {
+			ham := 0
+			for w, x := range b.bits[slot] {
+				ham += bits.OnesCount64(x ^ qb[w])
+			}
+			// Map to [-1, 1] so ordering matches "fewest differing signs"
+			return 1 - 2*float32(ham)/dim
+		}
+	}
+	panic("quantScorer on unquantized index")
+}
+
+// appendCodes encodes a newly appended slot. Caller holds mu.
+func (b *BruteForce) appendCodes(v []float32) {
+	switch b.opts.Quantization {
+	case QuantInt8:
+		b.codes8 = append(b.codes8, make([]int8, b.dim))
+		b.setCodes(len(b.codes8)-1, v)
+	case QuantBinary:
+		b.bits = append(b.bits, signBits(v))
+	}
+}
+
+// setCodes re-encodes slot i for vector v, widening int8 ranges (and
+// requantizing the affected dimensions of every slot) if v exceeds them.
+// Caller holds mu.
+func (b *BruteForce) setCodes(i int, v []float32) {
+	switch b.opts.Quantization {
+	case QuantInt8:
+		for d, x := range v {
+			if ax := float32(math.Abs(float64(x))); ax > b.scale[d] {
+				b.scale[d] = float32(math.Min(float64(ax)*scaleHeadroom, 1))
+				for j := range b.codes8 {
+					if j != i {
+						b.codes8[j][d] = quantize8(b.vecs[j][d], b.scale[d])
+					}
+				}
+			}
+			b.codes8[i][d] = quantize8(x, b.scale[d])
+		}
+	case QuantBinary:
+		b.bits[i] = signBits(v)
+	}
+}
+
+// removeCodes mirrors Remove's swap-delete of slot i with last. Caller
+// holds mu.
+func (b *BruteForce) removeCodes(i, last int) {
+	switch b.opts.Quantization {
+	case QuantInt8:
+		b.codes8[i] = b.codes8[last]
+		b.codes8[last] = nil
+		b.codes8 = b.codes8[:last]
+	case QuantBinary:
+		b.bits[i] = b.bits[last]
+		b.bits[last] = nil
+		b.bits = b.bits[:last]
+	}
+}
+
+// buildCodes encodes every slot from scratch, e.g. after loading a
+// snapshot. Caller holds mu or has exclusive access.
+func (b *BruteForce) buildCodes() {
+	switch b.opts.Quantization {
+	case QuantInt8:
+		for d := range b.scale {
+			b.scale[d] = 0
+		}
+		for _, v := range b.vecs {
+			for d, x := range v {
+				if ax := float32(math.Abs(float64(x))); ax > b.scale[d] {
+					b.scale[d] = ax
+				}
+			}
+		}
+		for d := range b.scale {
+			b.scale[d] = float32(math.Min(float64(b.scale[d])*scaleHeadroom, 1))
+		}
+		b.codes8 = make([][]int8, len(b.vecs))
+		for i, v := range b.vecs {
+			c := make([]int8, b.dim)
+			for d, x := range v {
+				c[d] = quantize8(x, b.scale[d])
+			}
+			b.codes8[i] = c
+		}
+	case QuantBinary:
+		b.bits = make([][]uint64, len(b.vecs))
+		for i, v := range b.vecs {
+			b.bits[i] = signBits(v)
+		}
+	}
+}
+
+// quantize8 maps x in [-scale, scale] to [-127, 127]
+func quantize8(x, scale float32) int8 {
+	if scale == 0 {
+		return 0
+	}
+	r := math.Round(float64(x / scale * 127))
+	if r > 127 {
+		r = 127
+	} else if r < -127 {
+		r = -127
+	}
+	return int8(r)
+}
+
+// signBits packs the sign of each component, one bit per dimension
+func signBits(v []float32) []uint64 {
+	out := make([]uint64, (len(v)+63)/64)
+	for d, x := range v {
+		if x > 0 {
+			out[d/64] |= 1 << (d % 64)
+		}
+	}
+	return out
+}
@@ -0,0 +1,284 @@
+package ann
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+var quantModes = []Quantization{QuantInt8, QuantBinary}
+
+// TestQuantizedFindsPlantedNeighbor is TestBruteForceFindsPlantedNeighbor
+// for each quantized mode: the rescoring pass must still surface the
+// near-duplicate first.
+func TestQuantizedFindsPlantedNeighbor(t *testing.T) {
+	for _, mode := range quantModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			const dim = 64
+			rng := rand.New(rand.NewSource(42))
+			idx := NewBruteForceWithOptions(dim, Options{Quantization: mode})
+
+			query := randVec(rng, dim)
+			planted := make([]float32, dim)
+			copy(planted, query)
+			planted[0] += 0.001
+
+			const plantedID = 999_999
+			if err := idx.Add(plantedID, planted); err != nil {
+				t.Fatalf("add planted: %v", err)
+			}
+			for i := 0; i < 5000; i++ {
+				if err := idx.Add(uint64(i), randVec(rng, dim)); err != nil {
+					t.Fatalf("add %d: %v", i, err)
+				}
+			}
+
+			ids, err := idx.Search(query, 10)
+			if err != nil {
+				t.Fatalf("search: %v", err)
+			}
+			if len(ids) != 10 {
+				t.Fatalf("expected 10 results, got %d", len(ids))
+			}
+			if ids[0] != plantedID {
+				t.Errorf("planted near-duplicate not ranked first; got id %d", ids[0])
+			}
+		})
+	}
+}
+
+// plantNeighbors adds n background vectors plus, for each query, a few
+// neighbors at graded distances, mimicking notes that genuinely match.
+// It returns the queries and each query's planted neighbor IDs.
+func plantNeighbors(t *testing.T, rng *rand.Rand, idxs []*BruteForce, dim, n, queries int) ([][]float32, [][]uint64) {
+	t.Helper()
+	add := func(id uint64, v []float32) {
+		for _, idx := range idxs {
+			if err := idx.Add(id, v); err != nil {
+				t.Fatalf("add %d: %v", id, err)
+			}
+		}
+	}
+	for i := 0; i < n; i++ {
+		add(uint64(i), randVec(rng, dim))
+	}
+	var qs [][]float32
+	var planted [][]uint64
+	id := uint64(n)
+	for q := 0; q < queries; q++ {
+		query := randVec(rng, dim)
+		qs = append(qs, query)
+		var ids []uint64
+		for j := 1; j <= 5; j++ {
+			v := make([]float32, dim)
+			for d := range v {
+				v[d] = query[d] + float32(j)*0.15*(rng.Float32()*2-1)
+			}
+			add(id, v)
+			ids = append(ids, id)
+			id++
+		}
+		planted = append(planted, ids)
+	}
+	return qs, planted
+}
+
+// TestQuantizedRecallAgainstFloatScan measures recall@k of each quantized
+// mode against the float32 scan over the same data, and checks that the
+// similarities returned are the exact float32 ones. Planted neighbors —
+// the results a user is actually looking for — must all be found; the
+// overall bound is looser for binary, whose sign bits cannot order the
+// near-random tail of background vectors.
+func TestQuantizedRecallAgainstFloatScan(t *testing.T) {
+	const dim = 96
+	const k = 10
+	minRecall := map[Quantization]float64{QuantInt8: 0.99, QuantBinary: 0.80}
+
+	for _, mode := range quantModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(17))
+			exact := NewBruteForce(dim)
+			quant := NewBruteForceWithOptions(dim, Options{Quantization: mode})
+			queries, planted := plantNeighbors(t, rng, []*BruteForce{exact, quant}, dim, 8000, 40)
+
+			found, total := 0, 0
+			for qi, q := range queries {
+				want, err := exact.SearchWithScores(q, k, nil)
+				if err != nil {
+					t.Fatalf("exact search: %v", err)
+				}
+				got, err := quant.SearchWithScores(q, k, nil)
+				if err != nil {
+					t.Fatalf("quantized search: %v", err)
+				}
+				if len(got) != k {
+					t.Fatalf("query %d: got %d results, want %d", qi, len(got), k)
+				}
+
+				exactSim := make(map[uint64]float32, len(want))
+				for _, h := range want {
+					exactSim[h.ID] = h.Sim
+				}
+				gotIDs := make(map[uint64]bool, len(got))
+				for i, h := range got {
+					gotIDs[h.ID] = true
+					if sim, ok := exactSim[h.ID]; ok {
+						found++
+						if sim != h.Sim {
+							t.Errorf("query %d id %d: sim %f, float scan has %f", qi, h.ID, h.Sim, sim)
+						}
+					}
+					if i > 0 && !worse(scored{id: h.ID, sim: h.Sim}, scored{id: got[i-1].ID, sim: got[i-1].Sim}) {
+						t.Errorf("query %d: results out of order at rank %d", qi, i)
+					}
+				}
+				total += k
+
+				for _, id := range planted[qi] {
+					if _, inExact := exactSim[id]; inExact && !gotIDs[id] {
+						t.Errorf("query %d: planted neighbor %d missed", qi, id)
+					}
+				}
+			}
+
+			recall := float64(found) / float64(total)
+			t.Logf("%s recall@%d = %.3f", mode, k, recall)
+			if recall < minRecall[mode] {
+				t.Errorf("recall@%d = %.3f, want >= %.2f", k, recall, minRecall[mode])
+			}
+		})
+	}
+}
+
+// TestQuantizedMutationsMatchFreshBuild replays Replace/Remove on a
+// quantized index and compares it with one built from the final vectors;
+// int8 ranges that grew along the way must not change results.
+func TestQuantizedMutationsMatchFreshBuild(t *testing.T) {
+	const dim = 32
+	for _, mode := range quantModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(23))
+			opts := Options{Quantization: mode}
+			idx := NewBruteForceWithOptions(dim, opts)
+
+			final := make(map[uint64][]float32)
+			for i := 0; i < 2000; i++ {
+				v := randVec(rng, dim)
+				if i%100 == 0 {
+					v[i%dim] = 40 // a large component forces an int8 range change
+				}
+				if err := idx.Add(uint64(i), v); err != nil {
+					t.Fatalf("add: %v", err)
+				}
+				final[uint64(i)] = v
+			}
+			for i := 0; i < 2000; i += 3 {
+				v := randVec(rng, dim)
+				if err := idx.Replace(uint64(i), v); err != nil {
+					t.Fatalf("replace: %v", err)
+				}
+				final[uint64(i)] = v
+			}
+			for i := 1; i < 2000; i += 7 {
+				if err := idx.Remove(uint64(i)); err != nil {
+					t.Fatalf("remove: %v", err)
+				}
+				delete(final, uint64(i))
+			}
+
+			fresh := NewBruteForceWithOptions(dim, opts)
+			for id, v := range final {
+				if err := fresh.Add(id, v); err != nil {
+					t.Fatalf("fresh add: %v", err)
+				}
+			}
+			if idx.Size() != fresh.Size() {
+				t.Fatalf("size: got %d, want %d", idx.Size(), fresh.Size())
+			}
+
+			for trial := 0; trial < 10; trial++ {
+				q := randVec(rng, dim)
+				got, _ := idx.SearchWithScores(q, 5, nil)
+				want, _ := fresh.SearchWithScores(q, 5, nil)
+				for i := range want {
+					if got[i] != want[i] {
+						t.Errorf("trial %d rank %d: got %+v, fresh build has %+v", trial, i, got[i], want[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestQuantizedSearchFiltered(t *testing.T) {
+	const dim = 32
+	for _, mode := range quantModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(29))
+			idx := NewBruteForceWithOptions(dim, Options{Quantization: mode})
+			for i := 0; i < 3000; i++ {
+				idx.Add(uint64(i), randVec(rng, dim))
+			}
+			accept := func(id uint64) bool { return id%10 == 3 }
+			ids, err := idx.SearchFiltered(randVec(rng, dim), 20, accept)
+			if err != nil {
+				t.Fatalf("search: %v", err)
+			}
+			if len(ids) != 20 {
+				t.Fatalf("expected a full 20 results, got %d", len(ids))
+			}
+			for _, id := range ids {
+				if !accept(id) {
+					t.Errorf("filtered-out id %d returned", id)
+				}
+			}
+		})
+	}
+}
+
+func TestQuantizedLoadFromSnapshot(t *testing.T) {
+	const dim = 48
+	rng := rand.New(rand.NewSource(31))
+	orig := buildIndex(t, rng, dim, 2000)
+	path := filepath.Join(t.TempDir(), "vectors.snap")
+	if err := orig.Save(path, "m", 1); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	for _, mode := range quantModes {
+		t.Run(mode.String(), func(t *testing.T) {
+			idx, _, err := LoadBruteForceWithOptions(path, Options{Quantization: mode})
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			defer idx.Close()
+
+			// Query with a stored vector: it must come back first at sim 1
+			target := 1 + 7*uint64(rng.Intn(2000))
+			q := idx.vecs[idx.pos[target]]
+			hits, err := idx.SearchWithScores(q, 3, nil)
+			if err != nil {
+				t.Fatalf("search: %v", err)
+			}
+			if hits[0].ID != target || math.Abs(float64(hits[0].Sim)-1) > 1e-5 {
+				t.Errorf("got %+v first, want id %d at sim 1", hits[0], target)
+			}
+		})
+	}
+}
+
+func TestParseQuantization(t *testing.T) {
+	for _, s := range []string{"none", "int8", "binary"} {
+		q, err := ParseQuantization(s)
+		if err != nil || q.String() != s {
+			t.Errorf("ParseQuantization(%q) = %v, %v", s, q, err)
+		}
+	}
+	if q, err := ParseQuantization(""); err != nil || q != QuantNone {
+		t.Errorf("empty string should mean none, got %v, %v", q, err)
+	}
+	if _, err := ParseQuantization("int4"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
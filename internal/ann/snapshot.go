@@ -0,0 +1,246 @@
+package ann
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"unsafe"
+)
+
+// Snapshot file layout (all integers little-endian):
+//
+//	magic     [8]byte  "OBSXSNAP"
+//	version   uint32
+//	dim       uint32
+//	count     uint64
+//	changeSeq int64    store change-log seq the contents reflect
+//	modelLen  uint32
+//	model     [modelLen]byte, zero-padded to an 8-byte boundary
+//	ids       [count]uint64
+//	vecs      [count*dim]float32, L2-normalized, row-major
+//	crc       uint32   CRC-32 (IEEE) of everything before it
+//
+// The matrix is 8-byte aligned in the file so it can be used in place from
+// a memory mapping.
+const (
+	snapshotMagic   = "OBSXSNAP"
+	snapshotVersion = 1
+	snapshotFixed   = 8 + 4 + 4 + 8 + 8 + 4
+)
+
+// ErrSnapshotCorrupt is returned when a snapshot file fails validation
+var ErrSnapshotCorrupt = errors.New("snapshot corrupt")
+
+// SnapshotHeader describes a saved snapshot
+type SnapshotHeader struct {
+	Dim       int
+	Model     string
+	Count     int
+	ChangeSeq int64
+}
+
+// Save writes the index to path as a snapshot tagged with the embedding
+// model and the store change seq its contents reflect. The file is written
+// beside path and renamed into place, so readers never see a partial
+// snapshot.
+func (b *BruteForce) Save(path, model string, changeSeq int64) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if err := b.writeSnapshot(tmp, model, changeSeq); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (b *BruteForce) writeSnapshot(w io.Writer, model string, changeSeq int64) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriterSize(io.MultiWriter(w, crc), 1<<20)
+
+	hdr := make([]byte, snapshotFixed, snapshotFixed+len(model)+8)
+	copy(hdr, snapshotMagic)
+	binary.LittleEndian.PutUint32(hdr[8:], snapshotVersion)
+	binary.LittleEndian.PutUint32(hdr[12:], uint32(b.dim))
+	binary.LittleEndian.PutUint64(hdr[16:], uint64(len(b.ids)))
+	binary.LittleEndian.PutUint64(hdr[24:], uint64(changeSeq))
+	binary.LittleEndian.PutUint32(hdr[32:], uint32(len(model)))
+	hdr = append(hdr, model...)
+	for len(hdr)%8 != 0 {
+		hdr = append(hdr, 0)
+	}
+	if _, err := bw.Write(hdr); err != nil {
+		return err
+	}
+
+	var buf [8]byte
+	for _, id := range b.ids {
+		binary.LittleEndian.PutUint64(buf[:], id)
+		if _, err := bw.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+	for _, v := range b.vecs {
+		for _, x := range v {
+			binary.LittleEndian.PutUint32(buf[:4], math.Float32bits(x))
+			if _, err := bw.Write(buf[:4]); err != nil {
+				return err
+			}
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	binary.LittleEndian.PutUint32(buf[:4], crc.Sum32())
+	_, err := w.Write(buf[:4])
+	return err
+}
+
+// LoadBruteForce opens a snapshot written by Save. Where the platform
+// allows, the vector matrix is memory-mapped and used in place rather than
+// copied; the mapping is released by Close. Vectors added or replaced
+// later live on the heap as usual.
+func LoadBruteForce(path string) (*BruteForce, SnapshotHeader, error) {
+	return LoadBruteForceWithOptions(path, Options{})
+}
+
+// LoadBruteForceWithOptions is LoadBruteForce for an index with the given
+// storage options. Snapshots hold float32 rows only; quantized codes are
+// rebuilt from them on load.
+func LoadBruteForceWithOptions(path string, opts Options) (*BruteForce, SnapshotHeader, error) {
+	data, release, err := mapFile(path)
+	if err != nil {
+		return nil, SnapshotHeader{}, err
+	}
+
+	b, hdr, err := parseSnapshot(data, opts)
+	if err != nil {
+		release()
+		return nil, SnapshotHeader{}, fmt.Errorf("%s: %w", path, err)
+	}
+	b.buildCodes()
+	b.release = release
+	return b, hdr, nil
+}
+
+// ReadSnapshotHeader reads just the header of a snapshot file
+func ReadSnapshotHeader(path string) (SnapshotHeader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return SnapshotHeader{}, err
+	}
+	defer f.Close()
+
+	fixed := make([]byte, snapshotFixed)
+	if _, err := io.ReadFull(f, fixed); err != nil {
+		return SnapshotHeader{}, fmt.Errorf("%w: short header", ErrSnapshotCorrupt)
+	}
+	hdr, modelLen, err := parseFixedHeader(fixed)
+	if err != nil {
+		return SnapshotHeader{}, err
+	}
+	model := make([]byte, modelLen)
+	if _, err := io.ReadFull(f, model); err != nil {
+		return SnapshotHeader{}, fmt.Errorf("%w: short header", ErrSnapshotCorrupt)
+	}
+	hdr.Model = string(model)
+	return hdr, nil
+}
+
+func parseFixedHeader(data []byte) (SnapshotHeader, int, error) {
+	if string(data[:8]) != snapshotMagic {
+		return SnapshotHeader{}, 0, fmt.Errorf("%w: bad magic", ErrSnapshotCorrupt)
+	}
+	if v := binary.LittleEndian.Uint32(data[8:]); v != snapshotVersion {
+		return SnapshotHeader{}, 0, fmt.Errorf("%w: unsupported version %d", ErrSnapshotCorrupt, v)
+	}
+	hdr := SnapshotHeader{
+		Dim:       int(binary.LittleEndian.Uint32(data[12:])),
+		Count:     int(binary.LittleEndian.Uint64(data[16:])),
+		ChangeSeq: int64(binary.LittleEndian.Uint64(data[24:])),
+	}
+	return hdr, int(binary.LittleEndian.Uint32(data[32:])), nil
+}
+
+// parseSnapshot validates data and builds an index over it. When the host
+// is little-endian and the matrix is suitably aligned, vectors alias data
+// directly; otherwise they are decoded into fresh slices.
+func parseSnapshot(data []byte, opts Options) (*BruteForce, SnapshotHeader, error) {
+	if len(data) < snapshotFixed+4 {
+		return nil, SnapshotHeader{}, fmt.Errorf("%w: truncated", ErrSnapshotCorrupt)
+	}
+	hdr, modelLen, err := parseFixedHeader(data)
+	if err != nil {
+		return nil, SnapshotHeader{}, err
+	}
+	if hdr.Dim <= 0 || hdr.Count < 0 {
+		return nil, SnapshotHeader{}, fmt.Errorf("%w: bad dimensions", ErrSnapshotCorrupt)
+	}
+
+	idsOff := (snapshotFixed + modelLen + 7) &^ 7
+	vecsOff := idsOff + 8*hdr.Count
+	end := vecsOff + 4*hdr.Count*hdr.Dim
+	if idsOff > len(data) || end+4 != len(data) {
+		return nil, SnapshotHeader{}, fmt.Errorf("%w: size mismatch", ErrSnapshotCorrupt)
+	}
+	if crc32.ChecksumIEEE(data[:end]) != binary.LittleEndian.Uint32(data[end:]) {
+		return nil, SnapshotHeader{}, fmt.Errorf("%w: checksum mismatch", ErrSnapshotCorrupt)
+	}
+	hdr.Model = string(data[snapshotFixed : snapshotFixed+modelLen])
+
+	b := NewBruteForceWithOptions(hdr.Dim, opts)
+	b.ids = make([]uint64, hdr.Count)
+	b.vecs = make([][]float32, hdr.Count)
+	for i := range b.ids {
+		id := binary.LittleEndian.Uint64(data[idsOff+8*i:])
+		if _, dup := b.pos[id]; dup {
+			return nil, SnapshotHeader{}, fmt.Errorf("%w: duplicate id %d", ErrSnapshotCorrupt, id)
+		}
+		b.ids[i] = id
+		b.pos[id] = i
+	}
+
+	if hdr.Count == 0 {
+		return b, hdr, nil
+	}
+	raw := data[vecsOff:end]
+	var matrix []float32
+	if littleEndian && uintptr(unsafe.Pointer(&raw[0]))%4 == 0 {
+		matrix = unsafe.Slice((*float32)(unsafe.Pointer(&raw[0])), hdr.Count*hdr.Dim)
+	} else {
+		matrix = make([]float32, hdr.Count*hdr.Dim)
+		for i := range matrix {
+			matrix[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+		}
+	}
+	for i := range b.vecs {
+		// Full slice expression: an append can never spill into the next row
+		b.vecs[i] = matrix[i*hdr.Dim : (i+1)*hdr.Dim : (i+1)*hdr.Dim]
+	}
+	return b, hdr, nil
+}
+
+var littleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
@@ -0,0 +1,163 @@
+package ann
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func buildIndex(t *testing.T, rng *rand.Rand, dim, n int) *BruteForce {
+	t.Helper()
+	idx := NewBruteForce(dim)
+	for i := 0; i < n; i++ {
+		if err := idx.Add(uint64(i*7+1), randVec(rng, dim)); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	}
+	return idx
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	const dim = 24
+	rng := rand.New(rand.NewSource(5))
+	orig := buildIndex(t, rng, dim, 1000)
+	path := filepath.Join(t.TempDir(), "vectors.snap")
+
+	if err := orig.Save(path, "nomic-embed-text", 42); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	hdr, err := ReadSnapshotHeader(path)
+	if err != nil {
+		t.Fatalf("read header: %v", err)
+	}
+	want := SnapshotHeader{Dim: dim, Model: "nomic-embed-text", Count: 1000, ChangeSeq: 42}
+	if hdr != want {
+		t.Errorf("header: got %+v, want %+v", hdr, want)
+	}
+
+	loaded, hdr, err := LoadBruteForce(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	defer loaded.Close()
+	if hdr != want {
+		t.Errorf("load header: got %+v, want %+v", hdr, want)
+	}
+	if loaded.Size() != orig.Size() {
+		t.Fatalf("size: got %d, want %d", loaded.Size(), orig.Size())
+	}
+
+	for trial := 0; trial < 5; trial++ {
+		q := randVec(rng, dim)
+		got, err := loaded.SearchWithScores(q, 20, nil)
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		exp, _ := orig.SearchWithScores(q, 20, nil)
+		for i := range exp {
+			if got[i] != exp[i] {
+				t.Errorf("trial %d rank %d: got %+v, want %+v", trial, i, got[i], exp[i])
+			}
+		}
+	}
+}
+
+// TestSnapshotMutableAfterLoad checks a loaded (possibly mmap-backed) index
+// accepts the same Add/Replace/Remove replay the server applies on top.
+func TestSnapshotMutableAfterLoad(t *testing.T) {
+	const dim = 8
+	rng := rand.New(rand.NewSource(6))
+	orig := buildIndex(t, rng, dim, 50)
+	path := filepath.Join(t.TempDir(), "vectors.snap")
+	if err := orig.Save(path, "m", 1); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	idx, _, err := LoadBruteForce(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	defer idx.Close()
+
+	target := randVec(rng, dim)
+	if err := idx.Replace(1, target); err != nil { // id 1 came from the snapshot
+		t.Fatalf("replace: %v", err)
+	}
+	if err := idx.Remove(8); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err := idx.Add(99999, randVec(rng, dim)); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	ids, err := idx.Search(target, 1)
+	if err != nil || len(ids) != 1 || ids[0] != 1 {
+		t.Errorf("replaced vector not found first: %v, %v", ids, err)
+	}
+	ids, _ = idx.Search(target, idx.Size())
+	for _, id := range ids {
+		if id == 8 {
+			t.Errorf("removed id 8 still returned")
+		}
+	}
+	if idx.Size() != 50 {
+		t.Errorf("size: got %d, want 50", idx.Size())
+	}
+}
+
+func TestSnapshotDetectsCorruption(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	orig := buildIndex(t, rng, 16, 100)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vectors.snap")
+	if err := orig.Save(path, "m", 3); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	good, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		mutate func([]byte) []byte
+	}{
+		{"flipped vector byte", func(b []byte) []byte { b[len(b)-100] ^= 0xff; return b }},
+		{"truncated", func(b []byte) []byte { return b[:len(b)-10] }},
+		{"trailing garbage", func(b []byte) []byte { return append(b, 0, 0, 0, 0) }},
+		{"bad magic", func(b []byte) []byte { b[0] = 'X'; return b }},
+		{"future version", func(b []byte) []byte { b[8] = 99; return b }},
+		{"empty file", func(b []byte) []byte { return nil }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			bad := filepath.Join(dir, tc.name)
+			data := tc.mutate(append([]byte(nil), good...))
+			if err := os.WriteFile(bad, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := LoadBruteForce(bad); !errors.Is(err, ErrSnapshotCorrupt) {
+				t.Errorf("expected ErrSnapshotCorrupt, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSnapshotEmptyIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.snap")
+	if err := NewBruteForce(4).Save(path, "m", 0); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	idx, hdr, err := LoadBruteForce(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if idx.Size() != 0 || hdr.Count != 0 || hdr.Dim != 4 {
+		t.Errorf("unexpected empty snapshot: size %d, header %+v", idx.Size(), hdr)
+	}
+	if err := idx.Add(1, []float32{1, 0, 0, 0}); err != nil {
+		t.Errorf("add after loading empty snapshot: %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"crypto/sha256"
 	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -12,14 +13,29 @@ type Chunk struct {
 	HeadingPath string // e.g., "Introduction > Setup > Installation"
 	Content     string
 	ChunkIndex  int
-	StartLine   int
+	StartLine   int // 0-based lines in the original file, front matter included
 	EndLine     int
+
+	// Context is prepended to Content when embedding (e.g. the note's
+	// title and aliases) but not stored or shown
+	Context string
+
 	// Metadata inherited from note
 	Status         string
 	Scope          string
 	NoteType       string
 	CategoryWeight float32  // Calculated from tags using weight config
-	Tags           []string // Tags from frontmatter (e.g., #permanent-note, #writerflow)
+	Tags           []string // Note tags: front matter plus inline #tags anywhere in the body
+	ChunkTags      []string // Inline #tags written in this chunk
+}
+
+// EmbedText is the text to embed for the chunk: its Context, if any, then
+// its Content
+func (c Chunk) EmbedText() string {
+	if c.Context == "" {
+		return c.Content
+	}
+	return c.Context + "\n\n" + c.Content
 }
 
 // IsHeadingOnly reports whether content has no body text: every non-blank
@@ -33,8 +49,8 @@ type Chunk struct {
 //
 // Note: lines are trimmed before the # check, so an indented "# shell
 // comment" inside an unfenced code block also matches. Accepted trade-off —
-// aligning with ChunkMarkdown's untrimmed heading detection is not worth
-// keeping such fragments in the index.
+// aligning with ChunkMarkdown's stricter heading detection (HeadingLevel,
+// outside code fences) is not worth keeping such fragments in the index.
 func IsHeadingOnly(content string) bool {
 	for _, line := range strings.Split(content, "\n") {
 		trimmed := strings.TrimSpace(line)
@@ -71,90 +87,447 @@ func isHorizontalRule(trimmed string) bool {
 	return true
 }
 
-// ChunkMarkdown splits markdown into semantic chunks
-// Strategy: chunk by headings, keeping reasonable size limits
-func ChunkMarkdown(markdown string) []Chunk {
-	var chunks []Chunk
+// FrontMatterLines returns how many leading lines of the note are a YAML
+// front matter block, delimiters included (0 if there is none). The block
+// opens with a line of exactly "---" and closes with "---" or "...", so a
+// note starting with a "----" rule keeps its body.
+func FrontMatterLines(lines []string) int {
+	delimiter := func(line string) string { return strings.TrimRight(line, " \t\r") }
+	if len(lines) < 3 || delimiter(lines[0]) != "---" {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		if d := delimiter(lines[i]); d == "---" || d == "..." {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// FenceMarker returns the ``` or ~~~ run opening a fenced code block, or
+// "" if line (with indentation trimmed) does not open one
+func FenceMarker(line string) string {
+	for _, c := range []string{"`", "~"} {
+		if strings.HasPrefix(line, c+c+c) {
+			n := len(line) - len(strings.TrimLeft(line, c))
+			return strings.Repeat(c, n)
+		}
+	}
+	return ""
+}
+
+// ClosesFence reports whether line (with indentation trimmed) closes the
+// block opened by marker: the same character, at least as many, and
+// nothing after it, so "```c" inside a ``` block does not close it
+func ClosesFence(line, marker string) bool {
+	return strings.HasPrefix(line, marker) && strings.TrimLeft(line, marker[:1]) == ""
+}
+
+// HeadingLevel returns the level of an ATX heading ("## Title" is 2), or 0
+// if line is not one. The #s must be followed by a space or end the line,
+// so "#tag" lines are not headings.
+func HeadingLevel(line string) int {
+	rest := strings.TrimLeft(line, "#")
+	level := len(line) - len(rest)
+	if level < 1 || level > 6 || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return 0
+	}
+	return level
+}
+
+// Block kinds. A fence, table, quote or list is kept whole in one chunk.
+const (
+	blockBlank     = iota // a blank line
+	blockParagraph        // consecutive lines of text; may split at sentences
+	blockHeading          // an ATX heading outside any other block
+	blockFence            // ``` or ~~~ code block, fences included
+	blockTable            // pipe table
+	blockQuote            // blockquote or callout ("> [!note]")
+	blockList             // list items with their continuation lines
+)
+
+// nextBlock returns the kind of the block starting at lines[i] and the
+// index of the line after it
+func nextBlock(lines []string, i int) (int, int) {
+	trimmed := strings.TrimSpace(lines[i])
+	indent := len(lines[i]) - len(strings.TrimLeft(lines[i], " \t"))
+
+	switch {
+	case trimmed == "":
+		return blockBlank, i + 1
+
+	case FenceMarker(trimmed) != "":
+		return blockFence, fenceEnd(lines, i)
+
+	case indent < 4 && HeadingLevel(trimmed) > 0:
+		return blockHeading, i + 1
+
+	case strings.HasPrefix(trimmed, ">"):
+		j := i + 1
+		for j < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[j]), ">") {
+			j++
+		}
+		return blockQuote, j
+
+	case isTableStart(lines, i):
+		header := tableColumns(trimmed)
+		j := i + 1
+		for j < len(lines) && isTableRow(strings.TrimSpace(lines[j]), header) {
+			j++
+		}
+		return blockTable, j
+
+	case isListItem(trimmed) && !isHorizontalRule(trimmed):
+		return blockList, listEnd(lines, i)
+	}
+
+	// A paragraph runs until a blank line or another kind of block
+	j := i + 1
+	for j < len(lines) && !startsBlock(lines, j) {
+		j++
+	}
+	return blockParagraph, j
+}
+
+// startsBlock reports whether lines[i] ends a paragraph: it is blank or
+// opens a block of another kind
+func startsBlock(lines []string, i int) bool {
+	trimmed := strings.TrimSpace(lines[i])
+	indent := len(lines[i]) - len(strings.TrimLeft(lines[i], " \t"))
+	return trimmed == "" ||
+		FenceMarker(trimmed) != "" ||
+		indent < 4 && HeadingLevel(trimmed) > 0 ||
+		strings.HasPrefix(trimmed, ">") ||
+		isTableStart(lines, i) ||
+		isListItem(trimmed) && !isHorizontalRule(trimmed)
+}
+
+// fenceEnd returns the index of the line after the fenced block opening at
+// lines[i]. An unclosed fence runs to the end of the note.
+func fenceEnd(lines []string, i int) int {
+	marker := FenceMarker(strings.TrimSpace(lines[i]))
+	for j := i + 1; j < len(lines); j++ {
+		if ClosesFence(strings.TrimSpace(lines[j]), marker) {
+			return j + 1
+		}
+	}
+	return len(lines)
+}
+
+// listEnd returns the index of the line after the list starting at
+// lines[i]: further items, indented continuation lines (nested fences
+// included), and blank lines between them
+func listEnd(lines []string, i int) int {
+	end := i + 1
+	for j := i + 1; j < len(lines); {
+		line := lines[j]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			j++ // part of the list only if more of it follows
+			continue
+		case FenceMarker(trimmed) != "" && line != trimmed:
+			j = fenceEnd(lines, j)
+		case isListItem(trimmed) && !isHorizontalRule(trimmed), line[0] == ' ' || line[0] == '\t':
+			j++
+		default:
+			return end
+		}
+		end = j
+	}
+	return end
+}
+
+// isListItem matches "- ", "* ", "+ ", "1. " and "1) " items, task items
+// included
+func isListItem(trimmed string) bool {
+	if len(trimmed) >= 2 && strings.ContainsRune("-*+", rune(trimmed[0])) && trimmed[1] == ' ' {
+		return true
+	}
+	digits := len(trimmed) - len(strings.TrimLeft(trimmed, "0123456789"))
+	return digits > 0 && digits <= 9 && len(trimmed) > digits+1 &&
+		(trimmed[digits] == '.' || trimmed[digits] == ')') && trimmed[digits+1] == ' '
+}
+
+// isTableStart reports whether a pipe table starts at lines[i]: a row
+// starting with "|", or any row with pipes above a delimiter row
+func isTableStart(lines []string, i int) bool {
+	trimmed := strings.TrimSpace(lines[i])
+	if !strings.Contains(trimmed, "|") {
+		return false
+	}
+	return strings.HasPrefix(trimmed, "|") ||
+		i+1 < len(lines) && isTableDelimiter(strings.TrimSpace(lines[i+1]))
+}
+
+// isTableRow matches a row continuing a table whose header has header
+// columns: one starting with "|", or with as many columns as the header,
+// so text with a "[[Note|alias]]" link after a table is not taken in
+func isTableRow(trimmed string, header int) bool {
+	if strings.HasPrefix(trimmed, "|") {
+		return true
+	}
+	return strings.Contains(trimmed, "|") && tableColumns(trimmed) == header
+}
+
+// tableColumns counts the cells of a table row. Escaped pipes and pipes
+// inside a wikilink do not separate cells.
+func tableColumns(trimmed string) int {
+	row := strings.TrimSuffix(strings.TrimPrefix(trimmed, "|"), "|")
+	cols, depth := 1, 0
+	for i := 0; i < len(row); i++ {
+		switch {
+		case row[i] == '\\':
+			i++
+		case strings.HasPrefix(row[i:], "[["):
+			depth++
+			i++
+		case strings.HasPrefix(row[i:], "]]") && depth > 0:
+			depth--
+			i++
+		case row[i] == '|' && depth == 0:
+			cols++
+		}
+	}
+	return cols
+}
+
+// isTableDelimiter matches a table's header delimiter row ("|---|:--:|")
+func isTableDelimiter(trimmed string) bool {
+	if !strings.Contains(trimmed, "|") || !strings.Contains(trimmed, "-") {
+		return false
+	}
+	return strings.Trim(trimmed, "|-: \t") == ""
+}
+
+// piece is a run of a note's text that stays in one chunk: a block, or a
+// sentence of a paragraph
+type piece struct {
+	text       string // as in the note, with its trailing newline or spaces
+	kind       int
+	start, end int    // 0-based lines in the note
+	path       string // heading path in effect, the piece's own heading included
+	tokens     int
+}
+
+// notePieces splits a note's body into blocks, front matter skipped, each
+// with the heading path it falls under
+func notePieces(markdown string, tok Tokenizer) []piece {
 	lines := strings.Split(markdown, "\n")
 
 	var (
-		currentChunk strings.Builder
-		currentPath  []string
-		chunkStart   int
-		lineNum      int
-		chunkIndex   int
+		pieces      []piece
+		currentPath []string
+		levels      []int // of each heading in currentPath
 	)
+	for lineNum := FrontMatterLines(lines); lineNum < len(lines); {
+		kind, next := nextBlock(lines, lineNum)
+		text := strings.Join(lines[lineNum:next], "\n") + "\n"
 
-	const maxChunkSize = 1000 // characters
+		if kind == blockHeading {
+			// Parse heading level and text
+			heading := strings.TrimSpace(text)
+			level := HeadingLevel(heading)
+			headingText := strings.TrimSpace(heading[level:])
 
-	flushChunk := func(endLine int) {
-		if currentChunk.Len() == 0 {
-			return
+			// Update heading path: the heading replaces any at its level
+			// or deeper, even when levels were skipped ("## A" then "## B")
+			n := len(levels)
+			for n > 0 && levels[n-1] >= level {
+				n--
+			}
+			currentPath = append(currentPath[:n], headingText)
+			levels = append(levels[:n], level)
 		}
 
-		chunks = append(chunks, Chunk{
-			HeadingPath: strings.Join(currentPath, " > "),
-			Content:     strings.TrimSpace(currentChunk.String()),
-			ChunkIndex:  chunkIndex,
-			StartLine:   chunkStart,
-			EndLine:     endLine,
+		pieces = append(pieces, piece{
+			text:   text,
+			kind:   kind,
+			start:  lineNum,
+			end:    next - 1,
+			path:   strings.Join(currentPath, " > "),
+			tokens: tok.CountTokens(text),
 		})
+		lineNum = next
+	}
+	return pieces
+}
+
+// sentenceEnd matches the end of a sentence and the space after it
+var sentenceEnd = regexp.MustCompile(`[.!?]["')\]*_]*\s+`)
 
-		currentChunk.Reset()
-		chunkIndex++
-		chunkStart = endLine + 1
+// sentences splits a paragraph piece into one piece per sentence
+func sentences(p piece, tok Tokenizer) []piece {
+	var out []piece
+	add := func(from, to int) {
+		seg := p.text[from:to]
+		out = append(out, piece{
+			text:   seg,
+			kind:   blockParagraph,
+			start:  p.start + strings.Count(p.text[:from], "\n"),
+			end:    p.start + strings.Count(p.text[:from]+strings.TrimRight(seg, " \t\n"), "\n"),
+			path:   p.path,
+			tokens: tok.CountTokens(seg),
+		})
 	}
+	from := 0
+	for _, m := range sentenceEnd.FindAllStringIndex(p.text, -1) {
+		if m[1] < len(p.text) {
+			add(from, m[1])
+			from = m[1]
+		}
+	}
+	add(from, len(p.text))
+	return out
+}
 
-	for lineNum = 0; lineNum < len(lines); lineNum++ {
-		line := lines[lineNum]
+// splitParagraphs replaces the paragraphs over maxTokens (all of them if
+// maxTokens < 0) with their sentences
+func splitParagraphs(pieces []piece, maxTokens int, tok Tokenizer) []piece {
+	var out []piece
+	for _, p := range pieces {
+		if p.kind == blockParagraph && p.tokens > maxTokens {
+			out = append(out, sentences(p, tok)...)
+		} else {
+			out = append(out, p)
+		}
+	}
+	return out
+}
 
-		// Check for markdown heading
-		if strings.HasPrefix(line, "#") {
-			// Flush previous chunk before starting new section
-			if currentChunk.Len() > 0 {
-				flushChunk(lineNum - 1)
-			}
+// layout is how assemble groups pieces into chunks
+type layout struct {
+	headingBreaks bool // every heading starts a chunk
+	atTarget      bool // close a chunk at the first boundary once it reaches TargetTokens
 
-			// Parse heading level and text
-			level := 0
-			for i := 0; i < len(line) && line[i] == '#'; i++ {
-				level++
-			}
-			headingText := strings.TrimSpace(line[level:])
+	// cut, if set, reports whether to close a chunk holding tokens tokens
+	// before pieces[i]
+	cut func(i, tokens int) bool
+}
 
-			// Update heading path
-			if level <= len(currentPath) {
-				currentPath = currentPath[:level-1]
-			}
-			if level == 0 {
-				currentPath = nil
+// assemble groups pieces into chunks. A chunk is closed before a piece
+// when l says so or when the piece would take it past MaxTokens, but never
+// while it holds only headings, blank lines and overlap, and never before
+// a blank line (blank lines stay with the text before them).
+func assemble(pieces []piece, opts ChunkOptions, l layout) []Chunk {
+	var (
+		chunks  []Chunk
+		current []piece // the chunk being built
+		carried int     // leading pieces of current repeated from the previous chunk
+		tokens  int     // in current
+		hasBody bool    // current holds more than headings, blank lines and overlap
+	)
+
+	add := func(p piece) {
+		current = append(current, p)
+		tokens += p.tokens
+		if p.kind != blockHeading && p.kind != blockBlank {
+			hasBody = true
+		}
+	}
+
+	// flushChunk closes the current chunk. With overlap the next one starts
+	// with its trailing sentences or blocks, up to OverlapTokens.
+	flushChunk := func(overlap bool) {
+		var b strings.Builder
+		for _, p := range current {
+			b.WriteString(p.text)
+		}
+
+		// Blank lines alone (typically between the front matter and the
+		// first heading) are not a chunk
+		if content := strings.TrimSpace(b.String()); content != "" {
+			path := current[0].path
+			if carried < len(current) {
+				path = current[carried].path
 			}
-			currentPath = append(currentPath, headingText)
+			chunks = append(chunks, Chunk{
+				HeadingPath: path,
+				Content:     content,
+				ChunkIndex:  len(chunks),
+				StartLine:   current[0].start,
+				EndLine:     current[len(current)-1].end,
+			})
+		}
 
-			// Add heading to chunk
-			currentChunk.WriteString(line)
-			currentChunk.WriteByte('\n')
-			continue
+		var carry []piece
+		if overlap && opts.OverlapTokens > 0 {
+			carry = overlapPieces(current, opts.OverlapTokens, opts.Tokenizer)
 		}
+		current, carried, tokens, hasBody = nil, len(carry), 0, false
+		for _, p := range carry {
+			current = append(current, p)
+			tokens += p.tokens
+		}
+	}
 
-		// Regular content line
-		currentChunk.WriteString(line)
-		currentChunk.WriteByte('\n')
+	for i, p := range pieces {
+		if p.kind == blockHeading && l.headingBreaks {
+			// Flush previous chunk before starting new section
+			if len(current) > 0 {
+				flushChunk(false)
+			}
+			add(p)
+			continue
+		}
 
-		// Split if chunk gets too large
-		if currentChunk.Len() > maxChunkSize {
-			flushChunk(lineNum)
+		if hasBody && p.kind != blockBlank && (opts.Split.allows(SplitParagraph) || p.kind == blockHeading) &&
+			(tokens+p.tokens > opts.MaxTokens || l.atTarget && tokens >= opts.TargetTokens ||
+				l.cut != nil && l.cut(i, tokens)) {
+			flushChunk(true)
 		}
+		add(p)
 	}
 
 	// Flush final chunk
-	if currentChunk.Len() > 0 {
-		flushChunk(lineNum - 1)
+	if len(current) > 0 {
+		flushChunk(false)
 	}
 
 	return chunks
 }
 
+// ChunkMarkdown splits markdown into semantic chunks sized by opts (zero
+// fields take their defaults; see ChunkOptions)
+// Strategy: chunk by headings, then split sections that grow past the
+// target size between paragraphs and blocks, and paragraphs too large for
+// a chunk between sentences. Fenced code blocks, tables, blockquotes
+// (callouts included) and lists are never split. Only ATX headings outside
+// those blocks start a section; a "# comment" in a code block does not.
+// Front matter is skipped; line numbers still count it, so they match the
+// file.
+func ChunkMarkdown(markdown string, opts ChunkOptions) []Chunk {
+	opts = opts.normalized()
+	pieces := notePieces(markdown, opts.Tokenizer)
+	if opts.Split.allows(SplitSentence) {
+		pieces = splitParagraphs(pieces, opts.MaxTokens, opts.Tokenizer)
+	}
+	return assemble(pieces, opts, layout{headingBreaks: true, atTarget: true})
+}
+
+// overlapPieces returns the trailing pieces of a chunk, paragraphs broken
+// into sentences, that fit in limit tokens. The chunk's first sentence or
+// block is never included, so a chunk is not repeated whole, nor are its
+// headings or leading blank lines.
+func overlapPieces(chunk []piece, limit int, tok Tokenizer) []piece {
+	units := splitParagraphs(chunk, -1, tok)
+
+	first := 0
+	for first < len(units) && (units[first].kind == blockHeading || units[first].kind == blockBlank) {
+		first++
+	}
+
+	i, total := len(units), 0
+	for i-1 > first && total+units[i-1].tokens <= limit {
+		i--
+		total += units[i].tokens
+	}
+	for i < len(units) && (units[i].kind == blockBlank || units[i].kind == blockHeading) {
+		i++
+	}
+	return units[i:]
+}
+
 // ComputeContentHash returns SHA256 hash of content
 func ComputeContentHash(content string) string {
 	h := sha256.Sum256([]byte(content))
@@ -1,6 +1,11 @@
 package chunker
 
-import "testing"
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestIsHeadingOnly(t *testing.T) {
 	tests := []struct {
@@ -37,3 +42,422 @@ func TestIsHeadingOnly(t *testing.T) {
 		})
 	}
 }
+
+func TestChunkMarkdownSkipsFrontMatter(t *testing.T) {
+	tests := []struct {
+		name      string
+		markdown  string
+		wantFirst string
+		wantStart int // 0-based line of the first chunk in the file
+	}{
+		{
+			name:      "front matter then heading",
+			markdown:  "---\nstatus: active\ntags:\n  - a\n---\n\n## Intro\nBody text.\n",
+			wantFirst: "## Intro\nBody text.",
+			wantStart: 6,
+		},
+		{
+			name:      "front matter then body",
+			markdown:  "---\ntitle: x\n---\nBody text.\n",
+			wantFirst: "Body text.",
+			wantStart: 3,
+		},
+		{
+			name:      "dots close the block",
+			markdown:  "---\ntitle: x\n...\nBody text.\n",
+			wantFirst: "Body text.",
+			wantStart: 3,
+		},
+		{
+			name:      "note starting with a rule",
+			markdown:  "----\nIntro text.\n...and more.\n---\nBody text.\n",
+			wantFirst: "----\nIntro text.\n...and more.\n---\nBody text.",
+			wantStart: 0,
+		},
+		{
+			name:      "note starting with a spaced rule",
+			markdown:  "--- ---\nIntro text.\n---\n",
+			wantFirst: "--- ---\nIntro text.\n---",
+			wantStart: 0,
+		},
+		{
+			name:      "delimiters with trailing whitespace",
+			markdown:  "--- \r\ntitle: x\r\n---\t\r\nBody text.\n",
+			wantFirst: "Body text.",
+			wantStart: 3,
+		},
+		{
+			name:      "no front matter",
+			markdown:  "Body text.\n\n## Next\nMore.\n",
+			wantFirst: "Body text.",
+			wantStart: 0,
+		},
+		{
+			name:      "unclosed block is content",
+			markdown:  "---\ntitle: x\nBody text.\n",
+			wantFirst: "---\ntitle: x\nBody text.",
+			wantStart: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := ChunkMarkdown(tt.markdown, ChunkOptions{})
+			if len(chunks) == 0 {
+				t.Fatal("no chunks")
+			}
+			if chunks[0].Content != tt.wantFirst {
+				t.Errorf("first chunk = %q, want %q", chunks[0].Content, tt.wantFirst)
+			}
+			if chunks[0].StartLine != tt.wantStart || chunks[0].ChunkIndex != 0 {
+				t.Errorf("first chunk starts at line %d (index %d), want line %d (index 0)",
+					chunks[0].StartLine, chunks[0].ChunkIndex, tt.wantStart)
+			}
+		})
+	}
+}
+
+func TestEmbedText(t *testing.T) {
+	c := Chunk{Content: "Body."}
+	if got := c.EmbedText(); got != "Body." {
+		t.Errorf("without context: %q", got)
+	}
+	c.Context = "title: Roadmap"
+	if got := c.EmbedText(); got != "title: Roadmap\n\nBody." {
+		t.Errorf("with context: %q", got)
+	}
+}
+
+func TestChunkMarkdownStructure(t *testing.T) {
+	type want struct {
+		path       string
+		content    string
+		start, end int
+	}
+	// Sized in characters: filler nearly fills a chunk, so whatever
+	// follows it must start a new one
+	opts := ChunkOptions{TargetTokens: 1000, MaxTokens: 1000, Tokenizer: charTokenizer{}}
+	filler := strings.TrimSpace(strings.Repeat("Retention depends on rollover. ", 30))
+	lines := func(prefix string, n int) string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = fmt.Sprintf("%s%02d with enough text to take up room", prefix, i)
+		}
+		return strings.Join(out, "\n")
+	}
+
+	fence := "```bash\n# install dependencies\n" + lines("echo step ", 12) + "\n```"
+	table := "| Plan | Rollover |\n|------|:--------:|\n" + lines("| plan ", 12)
+	callout := "> [!note] Rollover\n>\n" + lines("> remember ", 12)
+	list := "- first item\n  continued here\n\n- [ ] task\n  ```sh\n# indented fence\n  ```\n1. numbered\n" + lines("- item ", 10)
+	big := "```\n" + lines("log line ", 40) + "\n```"
+
+	tests := []struct {
+		name     string
+		markdown string
+		want     []want
+	}{
+		{
+			name:     "comment in code fence is not a heading",
+			markdown: "## Setup\n```bash\n# install\nnpm ci\n```\nDone.",
+			want:     []want{{"Setup", "## Setup\n```bash\n# install\nnpm ci\n```\nDone.", 0, 5}},
+		},
+		{
+			name:     "tilde fence",
+			markdown: "## Setup\n~~~python\n# comment\n~~~\n# Next\nBody.",
+			want: []want{
+				{"Setup", "## Setup\n~~~python\n# comment\n~~~", 0, 3},
+				{"Next", "# Next\nBody.", 4, 5},
+			},
+		},
+		{
+			name:     "unclosed fence runs to the end",
+			markdown: "## Setup\n```\n# comment\n## also code",
+			want:     []want{{"Setup", "## Setup\n```\n# comment\n## also code", 0, 3}},
+		},
+		{
+			name:     "tag line is not a heading",
+			markdown: "## Notes\n#permanent-note #writerflow\nBody.",
+			want:     []want{{"Notes", "## Notes\n#permanent-note #writerflow\nBody.", 0, 2}},
+		},
+		{
+			name:     "seven hashes is not a heading",
+			markdown: "####### not a heading\nBody.",
+			want:     []want{{"", "####### not a heading\nBody.", 0, 1}},
+		},
+		{
+			name:     "heading paths nest",
+			markdown: "# A\n## B\nb\n### C\nc\n## D\nd",
+			want: []want{
+				{"A", "# A", 0, 0},
+				{"A > B", "## B\nb", 1, 2},
+				{"A > B > C", "### C\nc", 3, 4},
+				{"A > D", "## D\nd", 5, 6},
+			},
+		},
+		{
+			name:     "sibling headings below a skipped level",
+			markdown: "## A\na\n## B\nb\n#### C\nc\n### D\nd",
+			want: []want{
+				{"A", "## A\na", 0, 1},
+				{"B", "## B\nb", 2, 3},
+				{"B > C", "#### C\nc", 4, 5},
+				{"B > D", "### D\nd", 6, 7},
+			},
+		},
+		{
+			name:     "fence is not split",
+			markdown: "## Code\n" + filler + "\n" + fence + "\nAfter.",
+			want: []want{
+				{"Code", "## Code\n" + filler, 0, 1},
+				{"Code", fence + "\nAfter.", 2, 17},
+			},
+		},
+		{
+			name:     "table is not split",
+			markdown: filler + "\n" + table,
+			want: []want{
+				{"", filler, 0, 0},
+				{"", table, 1, 14},
+			},
+		},
+		{
+			name:     "callout is not split",
+			markdown: filler + "\n" + callout,
+			want: []want{
+				{"", filler, 0, 0},
+				{"", callout, 1, 14},
+			},
+		},
+		{
+			name:     "list is not split",
+			markdown: filler + "\n" + list + "\n\nAfter.",
+			want: []want{
+				{"", filler, 0, 0},
+				{"", list + "\n\nAfter.", 1, 20},
+			},
+		},
+		{
+			name:     "block over the limit stays whole with its heading",
+			markdown: "## Logs\n" + big + "\nAfter.",
+			want: []want{
+				{"Logs", "## Logs\n" + big, 0, 42},
+				{"Logs", "After.", 43, 43},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := ChunkMarkdown(tt.markdown, opts)
+			if len(chunks) != len(tt.want) {
+				for _, c := range chunks {
+					t.Logf("%q lines %d-%d: %q", c.HeadingPath, c.StartLine, c.EndLine, c.Content)
+				}
+				t.Fatalf("got %d chunks, want %d", len(chunks), len(tt.want))
+			}
+			for i, w := range tt.want {
+				c := chunks[i]
+				if c.HeadingPath != w.path || c.Content != w.content || c.StartLine != w.start || c.EndLine != w.end {
+					t.Errorf("chunk %d = %q lines %d-%d %q\nwant %q lines %d-%d %q",
+						i, c.HeadingPath, c.StartLine, c.EndLine, c.Content, w.path, w.start, w.end, w.content)
+				}
+			}
+		})
+	}
+}
+
+func TestNextBlock(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		kind     int
+		end      int // index of the line after the block
+	}{
+		{"table stops at text with a link alias", "| Plan | Cost |\n|---|---|\n| a | 1 |\nSee [[Plans|plan notes]].", blockTable, 3},
+		{"table rows without outer pipes", "Plan | Cost\n--- | ---\na | 1\nb | 2\n\nAfter.", blockTable, 4},
+		{"table row with a link in a cell", "| Plan | Cost |\n|---|---|\n[[Plans|a]] | 1\nAfter.", blockTable, 3},
+		{"pipe in a paragraph", "See [[Plans|plan notes]]\nfor details.\n- item", blockParagraph, 2},
+		{"paragraph ends at a table", "Plans:\nPlan | Cost\n--- | ---", blockParagraph, 1},
+		{"paragraph ends at a fence", "Run:\n```\ncode\n```", blockParagraph, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, end := nextBlock(strings.Split(tt.markdown, "\n"), 0)
+			if kind != tt.kind || end != tt.end {
+				t.Errorf("nextBlock = kind %d ending %d, want kind %d ending %d", kind, end, tt.kind, tt.end)
+			}
+		})
+	}
+}
+
+// A hard-wrapped paragraph is scanned once, not once per way to split it
+func TestChunkMarkdownLongParagraph(t *testing.T) {
+	paragraph := strings.TrimSpace(strings.Repeat("Retention depends on rollover\n", 300))
+	start := time.Now()
+	chunks := ChunkMarkdown(paragraph, DefaultChunkOptions())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("chunking a 300-line paragraph took %v", elapsed)
+	}
+	if len(chunks) == 0 || chunks[0].StartLine != 0 || chunks[len(chunks)-1].EndLine != 299 {
+		t.Errorf("chunks do not cover the paragraph: %d chunks", len(chunks))
+	}
+}
+
+// charTokenizer counts one token per byte
+type charTokenizer struct{}
+
+func (charTokenizer) Name() string                { return "chars" }
+func (charTokenizer) CountTokens(text string) int { return len(text) }
+
+// wordTokenizer counts one token per word
+type wordTokenizer struct{}
+
+func (wordTokenizer) Name() string                { return "words" }
+func (wordTokenizer) CountTokens(text string) int { return len(strings.Fields(text)) }
+
+func TestChunkMarkdownOptions(t *testing.T) {
+	type want struct {
+		content    string
+		start, end int
+	}
+	// Six sentences of five words on one line
+	var sentence []string
+	for i := 1; i <= 6; i++ {
+		sentence = append(sentence, fmt.Sprintf("S%d one two three four.", i))
+	}
+	long := strings.Join(sentence, " ")
+	para := func(from, to int) string { return strings.Join(sentence[from-1:to], " ") }
+
+	tests := []struct {
+		name     string
+		markdown string
+		opts     ChunkOptions
+		want     []want
+	}{
+		{
+			name:     "closes at target between paragraphs",
+			markdown: "One a b c d.\n\nTwo a b c d.\n\nThree a b c d.",
+			opts:     ChunkOptions{TargetTokens: 8, MaxTokens: 100},
+			want: []want{
+				{"One a b c d.\n\nTwo a b c d.", 0, 3},
+				{"Three a b c d.", 4, 4},
+			},
+		},
+		{
+			name:     "closes before max",
+			markdown: "One a b c d.\n\nTwo a b c d e f g h.",
+			opts:     ChunkOptions{TargetTokens: 10, MaxTokens: 10},
+			want: []want{
+				{"One a b c d.", 0, 1},
+				{"Two a b c d e f g h.", 2, 2},
+			},
+		},
+		{
+			name:     "oversized paragraph splits at sentences",
+			markdown: long,
+			opts:     ChunkOptions{TargetTokens: 10, MaxTokens: 12},
+			want: []want{
+				{para(1, 2), 0, 0},
+				{para(3, 4), 0, 0},
+				{para(5, 6), 0, 0},
+			},
+		},
+		{
+			name:     "sentences keep their lines",
+			markdown: "Line one a b.\nLine two a b.\nLine three a b.",
+			opts:     ChunkOptions{TargetTokens: 8, MaxTokens: 8},
+			want: []want{
+				{"Line one a b.\nLine two a b.", 0, 1},
+				{"Line three a b.", 2, 2},
+			},
+		},
+		{
+			name:     "overlap repeats trailing sentences",
+			markdown: long,
+			opts:     ChunkOptions{TargetTokens: 15, MaxTokens: 20, OverlapTokens: 5},
+			want: []want{
+				{para(1, 3), 0, 0},
+				{para(3, 5), 0, 0},
+				{para(5, 6), 0, 0},
+			},
+		},
+		{
+			name:     "no overlap across headings",
+			markdown: "## A\n" + long + "\n## B\nShort.",
+			opts:     ChunkOptions{TargetTokens: 100, MaxTokens: 100, OverlapTokens: 5},
+			want: []want{
+				{"## A\n" + long, 0, 1},
+				{"## B\nShort.", 2, 3},
+			},
+		},
+		{
+			name:     "paragraph split keeps paragraphs whole",
+			markdown: long + "\n\nAfter.",
+			opts:     ChunkOptions{TargetTokens: 10, MaxTokens: 12, Split: SplitParagraph},
+			want: []want{
+				{long, 0, 1},
+				{"After.", 2, 2},
+			},
+		},
+		{
+			name:     "heading split keeps sections whole",
+			markdown: "## A\n" + long + "\n\nAfter.\n## B\nShort.",
+			opts:     ChunkOptions{TargetTokens: 10, MaxTokens: 12, Split: SplitHeading},
+			want: []want{
+				{"## A\n" + long + "\n\nAfter.", 0, 3},
+				{"## B\nShort.", 4, 5},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Tokenizer = wordTokenizer{}
+			chunks := ChunkMarkdown(tt.markdown, tt.opts)
+			if len(chunks) != len(tt.want) {
+				for _, c := range chunks {
+					t.Logf("lines %d-%d: %q", c.StartLine, c.EndLine, c.Content)
+				}
+				t.Fatalf("got %d chunks, want %d", len(chunks), len(tt.want))
+			}
+			for i, w := range tt.want {
+				c := chunks[i]
+				if c.Content != w.content || c.StartLine != w.start || c.EndLine != w.end || c.ChunkIndex != i {
+					t.Errorf("chunk %d = lines %d-%d %q\nwant lines %d-%d %q",
+						i, c.StartLine, c.EndLine, c.Content, w.start, w.end, w.content)
+				}
+			}
+		})
+	}
+}
+
+func TestChunkOptionsString(t *testing.T) {
+	if got, want := (ChunkOptions{}).String(), "target=384 max=512 overlap=0 split=sentence tokenizer=estimate"; got != want {
+		t.Errorf("zero options = %q, want %q", got, want)
+	}
+	if got, want := DefaultChunkOptions().String(), "target=384 max=512 overlap=64 split=sentence tokenizer=estimate"; got != want {
+		t.Errorf("default options = %q, want %q", got, want)
+	}
+	// Sizes that cannot work together are clamped
+	opts := ChunkOptions{TargetTokens: 100, MaxTokens: 50, OverlapTokens: 80, Split: SplitParagraph}
+	if got, want := opts.String(), "target=100 max=100 overlap=50 split=paragraph tokenizer=estimate"; got != want {
+		t.Errorf("clamped options = %q, want %q", got, want)
+	}
+}
+
+func TestEstimateTokenizer(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"one two three", 4},               // 3 words × 4/3
+		{"https://example.com/a/b/c/d", 7}, // 27 chars / 4
+	}
+	for _, tt := range tests {
+		if got := (EstimateTokenizer{}).CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package chunker
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Default chunk sizes, in tokens. nomic-embed-text reads far more than
+// this, but retrieval works best when a chunk holds about one idea.
+const (
+	DefaultTargetTokens  = 384
+	DefaultMaxTokens     = 512
+	DefaultOverlapTokens = 64
+)
+
+// SplitLevel is the finest boundary ChunkMarkdown may split a section at.
+// Coarser boundaries are always preferred: heading > paragraph > sentence.
+type SplitLevel string
+
+const (
+	SplitHeading   SplitLevel = "heading"   // one chunk per section, whatever its size
+	SplitParagraph SplitLevel = "paragraph" // also between paragraphs and blocks
+	SplitSentence  SplitLevel = "sentence"  // also between the sentences of a paragraph over MaxTokens
+)
+
+// ParseSplitLevel parses "heading", "paragraph" or "sentence"
+func ParseSplitLevel(s string) (SplitLevel, error) {
+	switch l := SplitLevel(strings.ToLower(strings.TrimSpace(s))); l {
+	case SplitHeading, SplitParagraph, SplitSentence:
+		return l, nil
+	}
+	return "", fmt.Errorf("invalid split level %q (want heading, paragraph or sentence)", s)
+}
+
+// allows reports whether splitting at l is allowed when the finest
+// allowed boundary is finest
+func (finest SplitLevel) allows(l SplitLevel) bool {
+	rank := map[SplitLevel]int{SplitHeading: 0, SplitParagraph: 1, SplitSentence: 2}
+	return rank[l] <= rank[finest]
+}
+
+// Tokenizer counts the tokens an embedding model would see in text. An
+// estimate is fine: it only sizes chunks.
+type Tokenizer interface {
+	Name() string // recorded with the chunker settings (see ChunkOptions.String)
+	CountTokens(text string) int
+}
+
+// EstimateTokenizer estimates tokens without a vocabulary: the larger of
+// 4/3 tokens per word (English prose) and one token per 4 characters
+// (code, URLs and other long runs).
+type EstimateTokenizer struct{}
+
+func (EstimateTokenizer) Name() string { return "estimate" }
+
+func (EstimateTokenizer) CountTokens(text string) int {
+	words := float64(len(strings.Fields(text))) * 4 / 3
+	chars := float64(utf8.RuneCountInString(text)) / 4
+	return int(math.Ceil(math.Max(words, chars)))
+}
+
+// ChunkOptions sizes the chunks ChunkMarkdown makes. A chunk is closed at
+// the first allowed boundary once it reaches TargetTokens, and before it
+// would exceed MaxTokens. Fenced code blocks, tables, callouts and lists
+// are never split, so one alone may exceed MaxTokens.
+type ChunkOptions struct {
+	TargetTokens  int        // default DefaultTargetTokens
+	MaxTokens     int        // default DefaultMaxTokens; at least TargetTokens
+	OverlapTokens int        // trailing text of a chunk repeated at the start of the next in the same section; 0 for none
+	Split         SplitLevel // finest boundary to split at; default SplitSentence
+	Tokenizer     Tokenizer  // default EstimateTokenizer
+}
+
+// DefaultChunkOptions returns the options the indexer uses by default
+func DefaultChunkOptions() ChunkOptions {
+	return ChunkOptions{
+		TargetTokens:  DefaultTargetTokens,
+		MaxTokens:     DefaultMaxTokens,
+		OverlapTokens: DefaultOverlapTokens,
+		Split:         SplitSentence,
+		Tokenizer:     EstimateTokenizer{},
+	}
+}
+
+// normalized fills in defaults and clamps sizes that cannot work together
+func (o ChunkOptions) normalized() ChunkOptions {
+	if o.TargetTokens <= 0 {
+		o.TargetTokens = DefaultTargetTokens
+	}
+	if o.MaxTokens <= 0 {
+		o.MaxTokens = DefaultMaxTokens
+	}
+	if o.MaxTokens < o.TargetTokens {
+		o.MaxTokens = o.TargetTokens
+	}
+	// More overlap than half a chunk would mostly repeat the previous one
+	if o.OverlapTokens > o.TargetTokens/2 {
+		o.OverlapTokens = o.TargetTokens / 2
+	}
+	if o.OverlapTokens < 0 {
+		o.OverlapTokens = 0
+	}
+	if o.Split == "" {
+		o.Split = SplitSentence
+	}
+	if o.Tokenizer == nil {
+		o.Tokenizer = EstimateTokenizer{}
+	}
+	return o
+}
+
+// String describes the options after defaults, e.g. "target=384 max=512
+// overlap=64 split=sentence tokenizer=estimate". The indexer records it to
+// notice when notes must be chunked again.
+func (o ChunkOptions) String() string {
+	o = o.normalized()
+	return fmt.Sprintf("target=%d max=%d overlap=%d split=%s tokenizer=%s",
+		o.TargetTokens, o.MaxTokens, o.OverlapTokens, o.Split, o.Tokenizer.Name())
+}
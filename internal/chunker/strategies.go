@@ -0,0 +1,242 @@
+package chunker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Chunker splits a note into chunks
+type Chunker interface {
+	Chunk(ctx context.Context, markdown string) ([]Chunk, error)
+
+	// String names the strategy and its settings. The indexer records it
+	// and chunks every note again when it changes.
+	String() string
+}
+
+// EmbedFunc embeds texts in one batch, e.g. embed.Embedder's EmbedDocuments
+type EmbedFunc func(ctx context.Context, texts []string) ([][]float32, error)
+
+// Strategy builds a Chunker from the shared size options. embed is nil if
+// no embedder is available; strategies that need one return an error.
+type Strategy func(opts ChunkOptions, embed EmbedFunc) (Chunker, error)
+
+// DefaultStrategy is the strategy used unless another is chosen
+const DefaultStrategy = "heading"
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]Strategy{
+		"heading": func(opts ChunkOptions, _ EmbedFunc) (Chunker, error) {
+			return HeadingChunker{Options: opts}, nil
+		},
+		"window": func(opts ChunkOptions, _ EmbedFunc) (Chunker, error) {
+			return WindowChunker{Options: opts}, nil
+		},
+		"note": func(opts ChunkOptions, _ EmbedFunc) (Chunker, error) {
+			return NoteChunker{Options: opts}, nil
+		},
+		"semantic": func(opts ChunkOptions, embed EmbedFunc) (Chunker, error) {
+			if embed == nil {
+				return nil, fmt.Errorf("the semantic chunker needs an embedder")
+			}
+			return SemanticChunker{Options: opts, Embed: embed}, nil
+		},
+	}
+)
+
+// Register makes a strategy available by name to New. It panics if the
+// name is taken, like database/sql.Register.
+func Register(name string, s Strategy) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+	if _, dup := strategies[name]; dup || s == nil {
+		panic("chunker: Register called twice or with nil for " + name)
+	}
+	strategies[name] = s
+}
+
+// Strategies returns the registered strategy names, sorted
+func Strategies() []string {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New builds the named strategy's Chunker
+func New(name string, opts ChunkOptions, embed EmbedFunc) (Chunker, error) {
+	strategiesMu.RLock()
+	s, ok := strategies[name]
+	strategiesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown chunker %q (want one of %s)", name, strings.Join(Strategies(), ", "))
+	}
+	return s(opts, embed)
+}
+
+// HeadingChunker is ChunkMarkdown: a chunk never spans headings
+type HeadingChunker struct {
+	Options ChunkOptions
+}
+
+func (c HeadingChunker) Chunk(_ context.Context, markdown string) ([]Chunk, error) {
+	return ChunkMarkdown(markdown, c.Options), nil
+}
+
+func (c HeadingChunker) String() string { return "heading " + c.Options.String() }
+
+// WindowChunker slides a window of about TargetTokens over the whole note,
+// splitting between paragraphs (and sentences of long ones) like
+// ChunkMarkdown but running on across headings, with overlap. A chunk's
+// heading path is the section it starts in.
+type WindowChunker struct {
+	Options ChunkOptions
+}
+
+func (c WindowChunker) Chunk(_ context.Context, markdown string) ([]Chunk, error) {
+	opts := c.Options.normalized()
+	pieces := notePieces(markdown, opts.Tokenizer)
+	if opts.Split.allows(SplitSentence) {
+		pieces = splitParagraphs(pieces, opts.MaxTokens, opts.Tokenizer)
+	}
+	return assemble(pieces, opts, layout{atTarget: true}), nil
+}
+
+func (c WindowChunker) String() string { return "window " + c.Options.String() }
+
+// NoteChunker keeps a note up to MaxTokens as a single chunk, so a short
+// note is found as a whole; longer notes are chunked by ChunkMarkdown.
+type NoteChunker struct {
+	Options ChunkOptions
+}
+
+func (c NoteChunker) Chunk(_ context.Context, markdown string) ([]Chunk, error) {
+	opts := c.Options.normalized()
+	pieces := notePieces(markdown, opts.Tokenizer)
+	tokens := 0
+	for _, p := range pieces {
+		tokens += p.tokens
+	}
+	if tokens > opts.MaxTokens {
+		return ChunkMarkdown(markdown, opts), nil
+	}
+
+	// One chunk, under the note's first heading if it has one
+	chunks := assemble(pieces, opts, layout{})
+	if len(chunks) == 1 {
+		chunks[0].HeadingPath = ""
+		for _, p := range pieces {
+			if p.kind == blockHeading {
+				chunks[0].HeadingPath = p.path
+				break
+			}
+		}
+	}
+	return chunks, nil
+}
+
+func (c NoteChunker) String() string { return "note " + c.Options.String() }
+
+// SemanticChunker splits sections where the topic shifts: it embeds every
+// sentence (and every code block, table, callout and list, which stay
+// whole) and closes a chunk where the similarity of neighbours drops below
+// Threshold, once the chunk holds a quarter of TargetTokens. Chunks still
+// never span headings or exceed MaxTokens. Embedding each sentence makes
+// indexing several times slower.
+type SemanticChunker struct {
+	Options ChunkOptions
+	Embed   EmbedFunc
+
+	// Threshold is the cosine similarity below which neighbours are cut
+	// apart. 0 picks one per note: a standard deviation below the mean.
+	Threshold float32
+}
+
+func (c SemanticChunker) Chunk(ctx context.Context, markdown string) ([]Chunk, error) {
+	opts := c.Options.normalized()
+	opts.Split = SplitSentence
+	pieces := splitParagraphs(notePieces(markdown, opts.Tokenizer), -1, opts.Tokenizer)
+
+	var (
+		body  []int // indexes of pieces with text to compare
+		texts []string
+	)
+	for i, p := range pieces {
+		if p.kind != blockHeading && p.kind != blockBlank {
+			body = append(body, i)
+			texts = append(texts, strings.TrimSpace(p.text))
+		}
+	}
+	if len(texts) < 2 {
+		return assemble(pieces, opts, layout{headingBreaks: true}), nil
+	}
+
+	vecs, err := c.Embed(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("embed sentences: %w", err)
+	}
+	if len(vecs) != len(texts) {
+		return nil, fmt.Errorf("embed sentences: got %d vectors for %d sentences", len(vecs), len(texts))
+	}
+
+	// similarity[i] compares body piece i with the one before it
+	similarity := make(map[int]float32, len(body)-1)
+	var sum, sumSq float64
+	for j := 1; j < len(body); j++ {
+		s := cosine(vecs[j-1], vecs[j])
+		similarity[body[j]] = s
+		sum += float64(s)
+		sumSq += float64(s) * float64(s)
+	}
+
+	threshold := c.Threshold
+	if threshold == 0 {
+		n := float64(len(similarity))
+		mean := sum / n
+		threshold = float32(mean - math.Sqrt(math.Max(sumSq/n-mean*mean, 0)))
+	}
+
+	minTokens := opts.TargetTokens / 4
+	return assemble(pieces, opts, layout{
+		headingBreaks: true,
+		cut: func(i, tokens int) bool {
+			s, ok := similarity[i]
+			return ok && s < threshold && tokens >= minTokens
+		},
+	}), nil
+}
+
+func (c SemanticChunker) String() string {
+	threshold := "auto"
+	if c.Threshold != 0 {
+		threshold = fmt.Sprintf("%g", c.Threshold)
+	}
+	return "semantic threshold=" + threshold + " " + c.Options.String()
+}
+
+// cosine is the cosine similarity of a and b (0 if either is zero or
+// their lengths differ)
+func cosine(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(na*nb))
+}
@@ -0,0 +1,101 @@
+package chunker
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStrategies(t *testing.T) {
+	opts := ChunkOptions{TargetTokens: 8, MaxTokens: 12, Tokenizer: wordTokenizer{}}
+
+	// Sentences about cats embed close together, as do those about taxes
+	embed := func(_ context.Context, texts []string) ([][]float32, error) {
+		vecs := make([][]float32, len(texts))
+		for i, text := range texts {
+			if strings.Contains(text, "Cats") {
+				vecs[i] = []float32{1, 0.1}
+			} else {
+				vecs[i] = []float32{0.1, 1}
+			}
+		}
+		return vecs, nil
+	}
+
+	tests := []struct {
+		strategy string
+		markdown string
+		want     []string // chunk contents
+		paths    []string
+	}{
+		{
+			strategy: "heading",
+			markdown: "# Pets\n## Cats\nCats purr.\n## Dogs\nDogs bark.",
+			want:     []string{"# Pets", "## Cats\nCats purr.", "## Dogs\nDogs bark."},
+			paths:    []string{"Pets", "Pets > Cats", "Pets > Dogs"},
+		},
+		{
+			strategy: "note",
+			markdown: "# Pets\n## Cats\nCats purr.\n## Dogs\nDogs bark.",
+			want:     []string{"# Pets\n## Cats\nCats purr.\n## Dogs\nDogs bark."},
+			paths:    []string{"Pets"},
+		},
+		{
+			// Too long for one chunk: chunked by heading
+			strategy: "note",
+			markdown: "## Cats\nCats purr all day long.\n## Dogs\nDogs bark all night long.",
+			want:     []string{"## Cats\nCats purr all day long.", "## Dogs\nDogs bark all night long."},
+			paths:    []string{"Cats", "Dogs"},
+		},
+		{
+			strategy: "window",
+			markdown: "## Cats\nCats purr.\n## Dogs\nDogs bark.\n\nDogs fetch sticks daily.",
+			want:     []string{"## Cats\nCats purr.\n## Dogs\nDogs bark.", "Dogs fetch sticks daily."},
+			paths:    []string{"Cats", "Dogs"},
+		},
+		{
+			strategy: "semantic",
+			markdown: "## Notes\nCats purr. Cats nap. Taxes are due. Taxes are high.",
+			want:     []string{"## Notes\nCats purr. Cats nap.", "Taxes are due. Taxes are high."},
+			paths:    []string{"Notes", "Notes"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			c, err := New(tt.strategy, opts, embed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chunks, err := c.Chunk(context.Background(), tt.markdown)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got, paths []string
+			for _, ch := range chunks {
+				got = append(got, ch.Content)
+				paths = append(paths, ch.HeadingPath)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunks = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("heading paths = %q, want %q", paths, tt.paths)
+			}
+			if !strings.HasPrefix(c.String(), tt.strategy+" ") {
+				t.Errorf("String() = %q", c.String())
+			}
+		})
+	}
+
+	if _, err := New("bogus", opts, embed); err == nil {
+		t.Error("unknown strategy accepted")
+	}
+	if _, err := New("semantic", opts, nil); err == nil {
+		t.Error("semantic strategy built without an embedder")
+	}
+	if got := Strategies(); !reflect.DeepEqual(got, []string{"heading", "note", "semantic", "window"}) {
+		t.Errorf("Strategies() = %q", got)
+	}
+}
@@ -3,10 +3,13 @@ package config
 import (
 	"encoding/json"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
-// TagWeight defines a weight multiplier for a specific tag
+// TagWeight defines a weight multiplier for a tag and its nested tags.
+// Tag may be a glob ("project/*", "*-note").
 type TagWeight struct {
 	Tag    string  `json:"tag"`
 	Weight float32 `json:"weight"`
@@ -120,14 +123,12 @@ func (c *WeightConfig) CalculateWeight(tags []string, status string) float32 {
 	matchedAny := false
 
 	if c.MultiplyTagWeights {
-		// Multiply all matching tag weights
+		// Multiply the weights of all matching tags
 		tagWeight = 1.0
 		for _, tag := range tags {
-			for _, tw := range c.TagWeights {
-				if matchTag(tag, tw.Tag) {
-					tagWeight *= tw.Weight
-					matchedAny = true
-				}
+			if w, ok := c.tagWeight(tag); ok {
+				tagWeight *= w
+				matchedAny = true
 			}
 		}
 		if !matchedAny {
@@ -136,13 +137,8 @@ func (c *WeightConfig) CalculateWeight(tags []string, status string) float32 {
 	} else {
 		// Use maximum weight among matching tags
 		for _, tag := range tags {
-			for _, tw := range c.TagWeights {
-				if matchTag(tag, tw.Tag) {
-					if tw.Weight > tagWeight {
-						tagWeight = tw.Weight
-					}
-					matchedAny = true
-				}
+			if w, ok := c.tagWeight(tag); ok && w > tagWeight {
+				tagWeight = w
 			}
 		}
 	}
@@ -159,17 +155,51 @@ func (c *WeightConfig) CalculateWeight(tags []string, status string) float32 {
 	return tagWeight * float32(statusWeight)
 }
 
-// matchTag checks if a tag matches, handling # prefix variations
-func matchTag(noteTag, configTag string) bool {
-	// Normalize both to remove # prefix
-	noteTag = normalizeTag(noteTag)
-	configTag = normalizeTag(configTag)
-	return noteTag == configTag
+// tagWeight returns the weight of the most specific rule matching a note
+// tag. Tags are hierarchical, so a rule for "project" also covers
+// "project/alpha/design" unless a rule for "project/alpha" (or a glob such
+// as "project/*") matches first. At each level an exact rule beats a glob.
+func (c *WeightConfig) tagWeight(noteTag string) (float32, bool) {
+	tag := normalizeTag(noteTag)
+	for tag != "" {
+		var glob *TagWeight
+		for i := range c.TagWeights {
+			tw := &c.TagWeights[i]
+			rule := normalizeTag(tw.Tag)
+			if rule == tag {
+				return tw.Weight, true
+			}
+			if glob == nil && isGlob(rule) {
+				if ok, _ := path.Match(rule, tag); ok {
+					glob = tw
+				}
+			}
+		}
+		if glob != nil {
+			return glob.Weight, true
+		}
+
+		// Try the parent tag
+		i := strings.LastIndexByte(tag, '/')
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+	return 0, false
 }
 
+// normalizeTag strips the # prefix and folds case: Obsidian tags are
+// case-insensitive
 func normalizeTag(tag string) string {
-	if len(tag) > 0 && tag[0] == '#' {
-		return tag[1:]
-	}
-	return tag
+	tag = strings.TrimSpace(tag)
+	tag = strings.TrimPrefix(tag, "#")
+	return strings.ToLower(strings.Trim(tag, "/"))
+}
+
+// isGlob reports whether a rule uses path.Match wildcards. '*' does not
+// cross '/', so "project/*" matches "project/alpha" directly and
+// "project/alpha/design" through its parent.
+func isGlob(rule string) bool {
+	return strings.ContainsAny(rule, "*?[")
 }
@@ -0,0 +1,45 @@
+package config
+
+import "testing"
+
+func TestCalculateWeightTagHierarchy(t *testing.T) {
+	cfg := &WeightConfig{
+		TagWeights: []TagWeight{
+			{Tag: "project", Weight: 1.2},
+			{Tag: "#project/legacy", Weight: 0.7},
+			{Tag: "area/*", Weight: 1.1},
+			{Tag: "*-note", Weight: 1.3},
+		},
+		DefaultWeight: 1.0,
+	}
+
+	tests := []struct {
+		tag  string
+		want float32
+	}{
+		{"project", 1.2},
+		{"#Project", 1.2},
+		{"project/alpha/design", 1.2},
+		{"project/legacy/api", 1.0}, // the specific rule wins; max never drops below default
+		{"projects", 1.0},           // not a child of project
+		{"area", 1.0},               // the glob needs a child
+		{"area/health", 1.1},
+		{"area/health/sleep", 1.1},
+		{"permanent-note", 1.3},
+		{"inbox", 1.0},
+	}
+	for _, tt := range tests {
+		if got := cfg.CalculateWeight([]string{tt.tag}, ""); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+
+	// Max across tags
+	if got := cfg.CalculateWeight([]string{"project/legacy", "permanent-note"}, ""); got != 1.3 {
+		t.Errorf("max: got %v", got)
+	}
+	cfg.MultiplyTagWeights = true
+	if got := cfg.CalculateWeight([]string{"project/legacy", "area/x"}, ""); got != float32(0.7)*float32(1.1) {
+		t.Errorf("multiply: got %v", got)
+	}
+}
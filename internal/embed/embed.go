@@ -6,9 +6,26 @@ import (
 
 // Embedder converts text into vector embeddings using Ollama
 type Embedder interface {
-	// Embed converts text into a vector
+	// Embed converts text into a vector, as is
 	Embed(ctx context.Context, text string) ([]float32, error)
 
+	// EmbedBatch converts several texts in one round trip where the
+	// backend supports it. It returns one vector per text, in order.
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+
+	// EmbedQuery embeds a search query, behind the query prefix
+	EmbedQuery(ctx context.Context, text string) ([]float32, error)
+
+	// EmbedDocuments embeds texts to be searched, each behind the document
+	// prefix, like EmbedBatch
+	EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error)
+
+	// Prefixes returns the prefixes EmbedQuery and EmbedDocuments add
+	Prefixes() Prefixes
+
+	// SetPrefixes replaces them; safe while embedding
+	SetPrefixes(p Prefixes)
+
 	// Dimension returns the embedding dimension
 	Dimension() int
 
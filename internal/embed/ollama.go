@@ -7,17 +7,32 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync/atomic"
 	"time"
 )
 
 // OllamaEmbedder uses Ollama's embedding API
 type OllamaEmbedder struct {
-	endpoint  string
-	model     string
-	dimension int
-	client    *http.Client
+	endpoint string
+	model    string
+	client   *http.Client
+
+	// dimension is learned from the first response unless given; the
+	// indexer's workers may be embedding concurrently when it is
+	dimension atomic.Int64
+
+	// prefixes may be swapped while queries embed (SetPrefixes)
+	prefixes atomic.Pointer[Prefixes]
+
+	// noBatch is set once the server has answered /api/embed with 404
+	// (Ollama before 0.3), after which batches go through Embed one by one.
+	noBatch atomic.Bool
 }
 
+// maxBatch caps the inputs sent per /api/embed request, keeping each
+// request well inside the client timeout on CPU-only machines.
+const maxBatch = 32
+
 // OllamaEmbedRequest is the request format for Ollama
 type OllamaEmbedRequest struct {
 	Model  string `json:"model"`
@@ -29,21 +44,64 @@ type OllamaEmbedResponse struct {
 	Embedding []float32 `json:"embedding"`
 }
 
+// OllamaBatchRequest is the request format for Ollama's /api/embed
+type OllamaBatchRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// OllamaBatchResponse is the response format from Ollama's /api/embed
+type OllamaBatchResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
 // NewOllamaEmbedder creates an embedder using Ollama
 // Default endpoint: http://localhost:11434
 // Recommended models: nomic-embed-text, all-minilm
+// Queries and documents get the model's task prefixes (PrefixesFor)
 func NewOllamaEmbedder(endpoint, model string, dimension int) *OllamaEmbedder {
 	if endpoint == "" {
 		endpoint = "http://localhost:11434"
 	}
-	return &OllamaEmbedder{
-		endpoint:  endpoint,
-		model:     model,
-		dimension: dimension,
+	o := &OllamaEmbedder{
+		endpoint: endpoint,
+		model:    model,
 		client: &http.Client{
 			Timeout: 60 * time.Second,
 		},
 	}
+	o.dimension.Store(int64(dimension))
+	o.SetPrefixes(PrefixesFor(model))
+	return o
+}
+
+// SetPrefixes overrides the model's task prefixes; the zero Prefixes
+// embeds queries and documents as they are
+func (o *OllamaEmbedder) SetPrefixes(p Prefixes) {
+	o.prefixes.Store(&p)
+}
+
+// Prefixes returns the task prefixes in use
+func (o *OllamaEmbedder) Prefixes() Prefixes {
+	return *o.prefixes.Load()
+}
+
+// EmbedQuery embeds a search query behind the query prefix
+func (o *OllamaEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	return o.Embed(ctx, o.Prefixes().Query+text)
+}
+
+// EmbedDocuments embeds texts behind the document prefix, in batches
+func (o *OllamaEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	prefix := o.Prefixes().Document
+	if prefix == "" {
+		return o.EmbedBatch(ctx, texts)
+	}
+	prefixed := make([]string, len(texts))
+	for i, text := range texts {
+		prefixed[i] = prefix + text
+	}
+	return o.EmbedBatch(ctx, prefixed)
 }
 
 // Embed calls Ollama to embed text
@@ -84,16 +142,101 @@ func (o *OllamaEmbedder) Embed(ctx context.Context, text string) ([]float32, err
 	}
 
 	// Update dimension if not set
-	if o.dimension == 0 {
-		o.dimension = len(embedResp.Embedding)
-	}
+	o.dimension.CompareAndSwap(0, int64(len(embedResp.Embedding)))
 
 	return embedResp.Embedding, nil
 }
 
+// EmbedBatch embeds texts through Ollama's /api/embed endpoint, maxBatch
+// inputs per request. Servers without that endpoint are detected on the
+// first 404 and served by per-text Embed calls from then on.
+func (o *OllamaEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxBatch {
+		end := start + maxBatch
+		if end > len(texts) {
+			end = len(texts)
+		}
+		vecs, err := o.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vecs...)
+	}
+	return out, nil
+}
+
+func (o *OllamaEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if o.noBatch.Load() {
+		return o.embedEach(ctx, texts)
+	}
+
+	reqBody, err := json.Marshal(OllamaBatchRequest{
+		Model: o.model,
+		Input: texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/embed", o.endpoint)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		// A 404 is also how Ollama reports an unknown model; only fall back
+		// when the endpoint itself is missing.
+		body, _ := io.ReadAll(resp.Body)
+		if bytes.Contains(body, []byte("model")) {
+			return nil, fmt.Errorf("ollama error %d: %s", resp.StatusCode, string(body))
+		}
+		o.noBatch.Store(true)
+		return o.embedEach(ctx, texts)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("ollama error %d: %s", resp.StatusCode, string(body))
+	}
+
+	var batchResp OllamaBatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&batchResp); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if len(batchResp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d inputs", len(batchResp.Embeddings), len(texts))
+	}
+
+	// Update dimension if not set
+	o.dimension.CompareAndSwap(0, int64(len(batchResp.Embeddings[0])))
+
+	return batchResp.Embeddings, nil
+}
+
+// embedEach embeds texts one request at a time
+func (o *OllamaEmbedder) embedEach(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec, err := o.Embed(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("input %d: %w", i, err)
+		}
+		out[i] = vec
+	}
+	return out, nil
+}
+
 // Dimension returns the embedding dimension
 func (o *OllamaEmbedder) Dimension() int {
-	return o.dimension
+	return int(o.dimension.Load())
 }
 
 // ModelName returns the model identifier
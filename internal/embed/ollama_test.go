@@ -0,0 +1,215 @@
+package embed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeOllama serves /api/embeddings and, unless legacy is set, /api/embed.
+// Each vector encodes its input's length so results can be matched to
+// inputs.
+type fakeOllama struct {
+	legacy bool
+
+	mu          sync.Mutex
+	batchCalls  int
+	singleCalls int
+	batchSizes  []int
+}
+
+func (f *fakeOllama) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vecFor := func(text string) []float32 { return []float32{float32(len(text)), 1} }
+
+	switch r.URL.Path {
+	case "/api/embed":
+		if f.legacy {
+			http.NotFound(w, r) // "404 page not found", as old Ollama answers
+			return
+		}
+		var req OllamaBatchRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		f.mu.Lock()
+		f.batchCalls++
+		f.batchSizes = append(f.batchSizes, len(req.Input))
+		f.mu.Unlock()
+		resp := OllamaBatchResponse{}
+		for _, text := range req.Input {
+			resp.Embeddings = append(resp.Embeddings, vecFor(text))
+		}
+		json.NewEncoder(w).Encode(resp)
+	case "/api/embeddings":
+		var req OllamaEmbedRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		f.mu.Lock()
+		f.singleCalls++
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(OllamaEmbedResponse{Embedding: vecFor(req.Prompt)})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func inputs(n int) []string {
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = strings.Repeat("x", i+1)
+	}
+	return texts
+}
+
+func checkVectors(t *testing.T, texts []string, vecs [][]float32) {
+	t.Helper()
+	if len(vecs) != len(texts) {
+		t.Fatalf("got %d vectors for %d inputs", len(vecs), len(texts))
+	}
+	for i, v := range vecs {
+		if int(v[0]) != len(texts[i]) {
+			t.Errorf("vector %d belongs to input of length %d, want %d", i, int(v[0]), len(texts[i]))
+		}
+	}
+}
+
+func TestEmbedBatchUsesBatchEndpoint(t *testing.T) {
+	fake := &fakeOllama{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	e := NewOllamaEmbedder(srv.URL, "m", 0)
+	texts := inputs(maxBatch + 5)
+	vecs, err := e.EmbedBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("EmbedBatch: %v", err)
+	}
+	checkVectors(t, texts, vecs)
+
+	if fake.batchCalls != 2 || fake.singleCalls != 0 {
+		t.Errorf("expected 2 batch requests and no single ones, got %d and %d", fake.batchCalls, fake.singleCalls)
+	}
+	if fmt.Sprint(fake.batchSizes) != fmt.Sprint([]int{maxBatch, 5}) {
+		t.Errorf("batch sizes %v, want [%d 5]", fake.batchSizes, maxBatch)
+	}
+	if e.Dimension() != 2 {
+		t.Errorf("dimension not learned from batch: %d", e.Dimension())
+	}
+}
+
+// Workers embedding their first chunks at once all learn the dimension;
+// run with -race
+func TestEmbedConcurrentlyLearnsDimension(t *testing.T) {
+	srv := httptest.NewServer(&fakeOllama{})
+	defer srv.Close()
+
+	e := NewOllamaEmbedder(srv.URL, "m", 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(single bool) {
+			defer wg.Done()
+			var err error
+			if single {
+				_, err = e.Embed(context.Background(), "x")
+			} else {
+				_, err = e.EmbedBatch(context.Background(), inputs(2))
+			}
+			if err != nil {
+				t.Error(err)
+			}
+		}(i%2 == 0)
+	}
+	wg.Wait()
+	if e.Dimension() != 2 {
+		t.Errorf("dimension %d, want 2", e.Dimension())
+	}
+}
+
+func TestEmbedBatchFallsBackOnOldOllama(t *testing.T) {
+	fake := &fakeOllama{legacy: true}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	e := NewOllamaEmbedder(srv.URL, "m", 0)
+	for round := 0; round < 2; round++ {
+		texts := inputs(3)
+		vecs, err := e.EmbedBatch(context.Background(), texts)
+		if err != nil {
+			t.Fatalf("round %d: EmbedBatch: %v", round, err)
+		}
+		checkVectors(t, texts, vecs)
+	}
+	if fake.singleCalls != 6 {
+		t.Errorf("expected 6 single requests, got %d", fake.singleCalls)
+	}
+	if !e.noBatch.Load() {
+		t.Error("missing batch endpoint not remembered")
+	}
+}
+
+func TestEmbedBatchUnknownModelIsAnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"model \"m\" not found, try pulling it first"}`)
+	}))
+	defer srv.Close()
+
+	e := NewOllamaEmbedder(srv.URL, "m", 0)
+	if _, err := e.EmbedBatch(context.Background(), inputs(2)); err == nil {
+		t.Fatal("expected an error for an unknown model")
+	}
+	if e.noBatch.Load() {
+		t.Error("unknown model must not disable batching")
+	}
+}
+
+func TestPrefixesFor(t *testing.T) {
+	nomic := Prefixes{Query: "search_query: ", Document: "search_document: "}
+	tests := []struct {
+		model string
+		want  Prefixes
+	}{
+		{"nomic-embed-text", nomic},
+		{"nomic-embed-text:v1.5", nomic},
+		{"library/Nomic-Embed-Text:latest", nomic},
+		{"mxbai-embed-large:335m", Prefixes{Query: bgeQuery}},
+		{"snowflake-arctic-embed-l", Prefixes{Query: bgeQuery}},
+		{"all-minilm", Prefixes{}},
+		{"nomic-embed-textual", Prefixes{}},
+		{"", Prefixes{}},
+	}
+	for _, tt := range tests {
+		if got := PrefixesFor(tt.model); got != tt.want {
+			t.Errorf("PrefixesFor(%q) = %+v, want %+v", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestEmbedQueryAndDocumentsAddPrefixes(t *testing.T) {
+	srv := httptest.NewServer(&fakeOllama{})
+	defer srv.Close()
+	ctx := context.Background()
+
+	e := NewOllamaEmbedder(srv.URL, "nomic-embed-text", 0)
+	vec, err := e.EmbedQuery(ctx, "xx")
+	if err != nil {
+		t.Fatalf("EmbedQuery: %v", err)
+	}
+	checkVectors(t, []string{"search_query: xx"}, [][]float32{vec})
+
+	texts := inputs(3)
+	vecs, err := e.EmbedDocuments(ctx, texts)
+	if err != nil {
+		t.Fatalf("EmbedDocuments: %v", err)
+	}
+	checkVectors(t, []string{"search_document: x", "search_document: xx", "search_document: xxx"}, vecs)
+
+	e.SetPrefixes(Prefixes{})
+	if vecs, err = e.EmbedDocuments(ctx, texts); err != nil {
+		t.Fatalf("EmbedDocuments without prefixes: %v", err)
+	}
+	checkVectors(t, texts, vecs)
+}
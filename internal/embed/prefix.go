@@ -0,0 +1,50 @@
+package embed
+
+import (
+	"strings"
+)
+
+// Prefixes are the task instructions an asymmetric embedding model expects
+// ahead of its input: queries and the documents they should find are
+// embedded differently. Models trained this way match noticeably worse on
+// raw text.
+type Prefixes struct {
+	Query    string `json:"query"`
+	Document string `json:"document"`
+}
+
+// bgeQuery is the query instruction of BGE-style retrieval models, which
+// embed documents as they are
+const bgeQuery = "Represent this sentence for searching relevant passages: "
+
+// profiles maps model name prefixes to the task prefixes their model cards
+// ask for. Models not listed (e.g. all-minilm) get none.
+var profiles = map[string]Prefixes{
+	"nomic-embed-text":       {Query: "search_query: ", Document: "search_document: "},
+	"mxbai-embed-large":      {Query: bgeQuery},
+	"snowflake-arctic-embed": {Query: bgeQuery},
+	"bge-large":              {Query: bgeQuery},
+	"bge-base":               {Query: bgeQuery},
+	"bge-small":              {Query: bgeQuery},
+	"e5":                     {Query: "query: ", Document: "passage: "},
+	"multilingual-e5":        {Query: "query: ", Document: "passage: "},
+}
+
+// PrefixesFor returns the task prefixes for an Ollama model name such as
+// "nomic-embed-text:v1.5" or "library/mxbai-embed-large". The tag and
+// namespace are ignored and the longest matching profile wins.
+func PrefixesFor(model string) Prefixes {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	name, _, _ = strings.Cut(name, ":")
+
+	var best string
+	for p := range profiles {
+		if len(p) > len(best) && (name == p || strings.HasPrefix(name, p+"-")) {
+			best = p
+		}
+	}
+	return profiles[best]
+}
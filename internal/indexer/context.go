@@ -0,0 +1,119 @@
+package indexer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/sethfair/obsidx/internal/metadata"
+)
+
+// DefaultContextTemplate names the note and the chunk's section, so
+// chunks under the same heading in different notes embed apart
+const DefaultContextTemplate = "{{.Title}}{{with .Aliases}} ({{.}}){{end}}{{with .HeadingPath}}\n{{.}}{{end}}"
+
+// contextConfigKey is the index_meta key holding the embedding context
+// settings (see contextConfig) of the last complete IndexVault
+const contextConfigKey = "embed_context_config"
+
+// ContextData is what a context template (SetContextTemplate) can use
+type ContextData struct {
+	Title       string // front matter title, else the file name without ".md"
+	Aliases     string // comma-separated
+	HeadingPath string // the chunk's section, e.g. "ADR-004 > Decision"
+	Path        string // relative to the vault
+	Folder      string // relative to the vault; "" at the top level
+	Tags        string // note tags, comma-separated
+	Type        string
+	Status      string
+	Scope       string
+
+	meta *metadata.NoteMetadata
+}
+
+// Prop renders a front matter property, e.g. {{.Prop "project"}} (see
+// metadata.NoteMetadata.PropertyText)
+func (d ContextData) Prop(key string) string {
+	if d.meta == nil {
+		return ""
+	}
+	return d.meta.PropertyText(key)
+}
+
+// SetContextTemplate sets a text/template, executed with ContextData for
+// every chunk, whose output is embedded ahead of the chunk (before any
+// SetContextProperties lines), e.g. DefaultContextTemplate. Like property
+// context it is not stored or shown. "" disables it.
+func (idx *Indexer) SetContextTemplate(text string) error {
+	if text == "" {
+		idx.contextTmpl, idx.contextTmplText = nil, ""
+		return nil
+	}
+	tmpl, err := template.New("context").Parse(text)
+	if err != nil {
+		return fmt.Errorf("parse context template: %w", err)
+	}
+	// Catch unknown fields now rather than on every note
+	if err := tmpl.Execute(&strings.Builder{}, ContextData{}); err != nil {
+		return fmt.Errorf("context template: %w", err)
+	}
+	idx.contextTmpl, idx.contextTmplText = tmpl, text
+	return nil
+}
+
+// contextData returns the template data of a note at path; the caller
+// fills in HeadingPath for each chunk
+func (idx *Indexer) contextData(path string, meta *metadata.NoteMetadata) ContextData {
+	rel, err := filepath.Rel(idx.vaultDir, path)
+	if err != nil {
+		rel = path
+	}
+	rel = filepath.ToSlash(rel)
+
+	title := meta.PropertyText("title")
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	folder := filepath.ToSlash(filepath.Dir(rel))
+	if folder == "." {
+		folder = ""
+	}
+	return ContextData{
+		Title:   title,
+		Aliases: meta.PropertyText("aliases"),
+		Path:    rel,
+		Folder:  folder,
+		Tags:    strings.Join(meta.Tags, ", "),
+		Type:    meta.Type,
+		Status:  meta.Status,
+		Scope:   meta.Scope,
+		meta:    meta,
+	}
+}
+
+// chunkContext is the text embedded ahead of a chunk: the rendered
+// template, then the property lines
+func (idx *Indexer) chunkContext(data ContextData, propLines []string) (string, error) {
+	var parts []string
+	if idx.contextTmpl != nil {
+		var b strings.Builder
+		if err := idx.contextTmpl.Execute(&b, data); err != nil {
+			return "", fmt.Errorf("context template: %w", err)
+		}
+		if text := strings.TrimSpace(b.String()); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	parts = append(parts, propLines...)
+	return strings.Join(parts, "\n"), nil
+}
+
+// contextConfig describes the embedding context settings for
+// contextConfigKey; "" when there is no context
+func (idx *Indexer) contextConfig() string {
+	if idx.contextTmplText == "" && len(idx.contextProps) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("template=%q properties=%s", idx.contextTmplText, strings.Join(idx.contextProps, ","))
+}
@@ -3,11 +3,15 @@ package indexer
 import (
 	"context"
 	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/sethfair/obsidx/internal/ann"
@@ -18,6 +22,11 @@ import (
 	"github.com/sethfair/obsidx/internal/store"
 )
 
+// DefaultConcurrency is the default number of IndexVault parse and embed
+// workers each. Embedding dominates, and Ollama serves a few concurrent
+// requests well on typical hardware.
+const DefaultConcurrency = 4
+
 // Indexer manages the indexing process
 type Indexer struct {
 	store        *store.SQLite
@@ -25,8 +34,24 @@ type Indexer struct {
 	annIndex     ann.Index
 	vaultDir     string
 	weightConfig *config.WeightConfig
+	concurrency  int
+	contextProps []string // front matter keys embedded with every chunk
+
+	contextTmpl     *template.Template // SetContextTemplate; nil for none
+	contextTmplText string
+	chunkOpts       chunker.ChunkOptions
+	strategies      map[string]string // chunker strategy by folder relative to the vault; "" for the vault
+
+	// reparse makes checkFile pass unchanged files on, during an IndexVault
+	// whose chunkers or embedding context differ from those the notes were
+	// indexed with
+	reparse bool
 }
 
+// chunkerConfigKey is the index_meta key holding the chunkers (see
+// chunkerConfig) of the last complete IndexVault
+const chunkerConfigKey = "chunker_config"
+
 // New creates a new indexer
 func New(st *store.SQLite, embedder embed.Embedder, annIndex ann.Index, vaultDir string) *Indexer {
 	return &Indexer{
@@ -35,6 +60,9 @@ func New(st *store.SQLite, embedder embed.Embedder, annIndex ann.Index, vaultDir
 		annIndex:     annIndex,
 		vaultDir:     vaultDir,
 		weightConfig: nil, // Will use legacy weights if not set
+		concurrency:  DefaultConcurrency,
+		chunkOpts:    chunker.DefaultChunkOptions(),
+		strategies:   map[string]string{"": chunker.DefaultStrategy},
 	}
 }
 
@@ -43,27 +71,156 @@ func (idx *Indexer) SetWeightConfig(cfg *config.WeightConfig) {
 	idx.weightConfig = cfg
 }
 
-// IndexFile processes a single file// IndexFile processes a single file
+// SetConcurrency sets how many files IndexVault parses and embeds in
+// parallel (each stage gets n workers). Writes stay on a single goroutine
+// whatever n is; n <= 1 processes one file at a time.
+func (idx *Indexer) SetConcurrency(n int) {
+	idx.concurrency = n
+}
+
+// SetContextProperties names front matter properties (e.g. "title",
+// "aliases") whose values are embedded ahead of every chunk of the note,
+// so a chunk can match on what its note is called. They are not part of
+// the stored chunk content.
+func (idx *Indexer) SetContextProperties(keys []string) {
+	idx.contextProps = keys
+}
+
+// SetChunkOptions sets the chunk sizes every chunker strategy works to.
+// When the chunking differs from that recorded by the last IndexVault, the
+// next IndexVault chunks every note again; unchanged chunks keep their
+// embeddings.
+func (idx *Indexer) SetChunkOptions(opts chunker.ChunkOptions) {
+	idx.chunkOpts = opts
+}
+
+// SetChunkStrategy picks the chunker strategy (see chunker.Strategies) for
+// notes under folder, relative to the vault, or for the whole vault if
+// folder is "". Subfolders are included; the deepest folder set wins.
+func (idx *Indexer) SetChunkStrategy(folder, name string) error {
+	if _, err := chunker.New(name, idx.chunkOpts, idx.embedder.EmbedDocuments); err != nil {
+		return err
+	}
+	idx.strategies[strings.Trim(filepath.ToSlash(folder), "/")] = name
+	return nil
+}
+
+// strategyFor returns the chunker strategy for the note at path
+func (idx *Indexer) strategyFor(path string) string {
+	rel, err := filepath.Rel(idx.vaultDir, path)
+	if err != nil {
+		rel = path
+	}
+	rel = filepath.ToSlash(rel)
+
+	name, depth := chunker.DefaultStrategy, -1
+	for folder, s := range idx.strategies {
+		if len(folder) > depth && (folder == "" || strings.HasPrefix(rel, folder+"/")) {
+			name, depth = s, len(folder)
+		}
+	}
+	return name
+}
+
+// chunkerConfig describes the chunkers in use, e.g. "heading target=384
+// ...; Journal: note target=384 ...", for chunkerConfigKey
+func (idx *Indexer) chunkerConfig() string {
+	folders := make([]string, 0, len(idx.strategies))
+	for folder := range idx.strategies {
+		folders = append(folders, folder)
+	}
+	sort.Strings(folders)
+
+	parts := make([]string, 0, len(folders))
+	for _, folder := range folders {
+		c, err := chunker.New(idx.strategies[folder], idx.chunkOpts, idx.embedder.EmbedDocuments)
+		if err != nil {
+			continue // rejected by SetChunkStrategy
+		}
+		if folder == "" {
+			parts = append(parts, c.String())
+		} else {
+			parts = append(parts, folder+": "+c.String())
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// fileJob carries one file through indexing: checkFile fills in the hash
+// and rename verdict, parseFile the chunks, embedFile the vectors, and
+// writeFile commits it.
+type fileJob struct {
+	path   string
+	hash   string
+	mtime  int64
+	rename bool // untracked, and its content matches a tracked file gone from disk
+
+	chunks  []chunker.Chunk
+	links   []store.Link
+	toEmbed []int // indexes into chunks worth embedding
+	vecs    [][]float32
+	hashes  []string // embedding input hash of each toEmbed chunk
+	reused  int      // vectors copied from unchanged input rather than embedded
+}
+
+// IndexFile processes a single file
 func (idx *Indexer) IndexFile(ctx context.Context, path string) error {
+	job, err := idx.checkFile(ctx, path)
+	if err != nil || job == nil {
+		return err
+	}
+
+	// Re-key the chunks of a renamed file instead of re-embedding them
+	if job.rename {
+		return idx.writeRename(ctx, job)
+	}
+
+	if err := idx.parseFile(ctx, job); err != nil {
+		return err
+	}
+	idx.embedFile(ctx, job)
+	return idx.writeFile(ctx, job)
+}
+
+// checkFile hashes path and compares it with the stored file info. It
+// returns nil if the file is unchanged. It only reads from the store.
+func (idx *Indexer) checkFile(ctx context.Context, path string) (*fileJob, error) {
 	// Compute file hash
 	fileHash, mtime, err := computeFileHash(path)
 	if err != nil {
-		return fmt.Errorf("hash file: %w", err)
+		return nil, fmt.Errorf("hash file: %w", err)
 	}
 
 	// Check if file has changed
 	existing, err := idx.store.GetFileInfo(ctx, path)
 	if err != nil {
-		return fmt.Errorf("get file info: %w", err)
+		return nil, fmt.Errorf("get file info: %w", err)
 	}
 
-	if existing != nil && existing.SHA256 == fileHash {
+	if existing != nil && existing.SHA256 == fileHash && !idx.reparse {
 		// File unchanged, skip
-		return nil
+		return nil, nil
 	}
 
+	job := &fileJob{path: path, hash: fileHash, mtime: mtime}
+
+	// An untracked file whose content matches a tracked file that no
+	// longer exists is a rename: re-key the chunks instead of re-embedding.
+	if existing == nil {
+		source, err := idx.renameSource(ctx, path, fileHash)
+		if err != nil {
+			return nil, fmt.Errorf("rename: %w", err)
+		}
+		job.rename = source != ""
+	}
+	return job, nil
+}
+
+// parseFile reads and chunks the file, applies note metadata and picks
+// the chunks worth embedding.
+func (idx *Indexer) parseFile(ctx context.Context, job *fileJob) error {
 	// Read and chunk file
-	content, err := os.ReadFile(path)
+	content, err := os.ReadFile(job.path)
 	if err != nil {
 		return fmt.Errorf("read file: %w", err)
 	}
@@ -73,16 +230,43 @@ func (idx *Indexer) IndexFile(ctx context.Context, path string) error {
 	// Extract metadata from front matter
 	noteMeta := metadata.ParseFrontMatter(contentStr)
 
+	// NOTE: an empty chunk list must NOT short-circuit here — a file edited
+	// down to nothing still needs its old chunks deactivated and its hash
+	// recorded, or search serves deleted content forever (see writeFile).
+	c, err := chunker.New(idx.strategyFor(job.path), idx.chunkOpts, idx.embedder.EmbedDocuments)
+	if err != nil {
+		return err
+	}
+	chunks, err := c.Chunk(ctx, contentStr)
+	if err != nil {
+		return fmt.Errorf("chunk: %w", err)
+	}
+
+	// Inline #tags count as note tags too, as in Obsidian
+	for i := range chunks {
+		chunks[i].ChunkTags = metadata.InlineTags(chunks[i].Content)
+		noteMeta.Tags = metadata.MergeTags(noteMeta.Tags, chunks[i].ChunkTags)
+	}
+
 	// Calculate weight from tags and status
 	categoryWeight := noteMeta.CalculateWeight(idx.weightConfig)
 
-	// NOTE: an empty chunk list must NOT short-circuit here — a file edited
-	// down to nothing still needs its old chunks deactivated and its hash
-	// recorded, or search serves deleted content forever (see the tx below).
-	chunks := chunker.ChunkMarkdown(contentStr)
+	// Property and template context (title, heading path, ...) is
+	// embedded, not stored
+	var propLines []string
+	for _, key := range idx.contextProps {
+		if text := noteMeta.PropertyText(key); text != "" {
+			propLines = append(propLines, key+": "+text)
+		}
+	}
+	contextData := idx.contextData(job.path, noteMeta)
 
 	// Apply metadata to all chunks
 	for i := range chunks {
+		contextData.HeadingPath = chunks[i].HeadingPath
+		if chunks[i].Context, err = idx.chunkContext(contextData, propLines); err != nil {
+			return err
+		}
 		chunks[i].Status = noteMeta.Status
 		chunks[i].Scope = noteMeta.Scope
 		chunks[i].NoteType = noteMeta.Type
@@ -90,15 +274,8 @@ func (idx *Indexer) IndexFile(ctx context.Context, path string) error {
 		chunks[i].Tags = noteMeta.Tags // Store tags for display/filtering
 	}
 
-	// Embed chunks, skipping empty ones
-	type chunkWithVector struct {
-		chunk  chunker.Chunk
-		vector []float32
-		index  int
-	}
-
-	validChunks := make([]chunkWithVector, 0, len(chunks))
-
+	// Select chunks to embed, skipping empty ones
+	var toEmbed []int
 	for i, chunk := range chunks {
 		// Skip chunks that are too short
 		trimmed := strings.TrimSpace(chunk.Content)
@@ -113,11 +290,48 @@ func (idx *Indexer) IndexFile(ctx context.Context, path string) error {
 			continue
 		}
 
-		vec, err := idx.embedder.Embed(ctx, chunk.Content)
-		if err != nil {
-			// Log but continue with other chunks
-			fmt.Printf("  Warning: embed chunk %d failed: %v\n", i, err)
-			continue
+		toEmbed = append(toEmbed, i)
+	}
+
+	job.chunks = chunks
+	job.links = noteLinks(contentStr)
+	job.toEmbed = toEmbed
+	return nil
+}
+
+// noteLinks extracts a note's links for the store
+func noteLinks(content string) []store.Link {
+	parsed := metadata.ExtractLinks(content)
+	links := make([]store.Link, len(parsed))
+	for i, l := range parsed {
+		links[i] = store.Link{
+			Line:     l.Line,
+			LinkType: l.Type,
+			Target:   l.Target,
+			Heading:  l.Heading,
+			Alias:    l.Alias,
+		}
+	}
+	return links
+}
+
+// writeFile replaces the file's chunks in one transaction and then mirrors
+// the change in the search index. It is the only stage that writes.
+func (idx *Indexer) writeFile(ctx context.Context, job *fileJob) error {
+	path := job.path
+
+	type chunkWithVector struct {
+		chunk     chunker.Chunk
+		vector    []float32
+		inputHash string
+		index     int
+	}
+
+	validChunks := make([]chunkWithVector, 0, len(job.toEmbed))
+	for j, i := range job.toEmbed {
+		vec := job.vecs[j]
+		if vec == nil {
+			continue // embedding failed, already logged
 		}
 
 		// Skip empty or zero-norm embeddings — both mean the embedder
@@ -137,9 +351,10 @@ func (idx *Indexer) IndexFile(ctx context.Context, path string) error {
 		}
 
 		validChunks = append(validChunks, chunkWithVector{
-			chunk:  chunk,
-			vector: vec,
-			index:  i,
+			chunk:     job.chunks[i],
+			vector:    vec,
+			inputHash: job.hashes[j],
+			index:     i,
 		})
 	}
 
@@ -156,11 +371,13 @@ func (idx *Indexer) IndexFile(ctx context.Context, path string) error {
 	defer tx.Rollback()
 
 	// Mark existing chunks inactive
-	if err := idx.store.MarkChunksInactive(ctx, tx, path); err != nil {
+	staleIDs, err := idx.store.MarkChunksInactive(ctx, tx, path)
+	if err != nil {
 		return fmt.Errorf("mark chunks inactive: %w", err)
 	}
 
 	// Insert new chunks and embeddings
+	addedIDs := make([]int64, 0, len(validChunks))
 	for _, cwv := range validChunks {
 		storeChunk := &store.Chunk{
 			Path:           path,
@@ -175,6 +392,7 @@ func (idx *Indexer) IndexFile(ctx context.Context, path string) error {
 			NoteType:       cwv.chunk.NoteType,
 			CategoryWeight: cwv.chunk.CategoryWeight,
 			Tags:           cwv.chunk.Tags,
+			ChunkTags:      cwv.chunk.ChunkTags,
 		}
 
 		chunkID, err := idx.store.InsertChunk(ctx, tx, storeChunk)
@@ -183,22 +401,95 @@ func (idx *Indexer) IndexFile(ctx context.Context, path string) error {
 		}
 
 		embedding := &store.Embedding{
-			ChunkID: chunkID,
-			Dim:     len(cwv.vector),
-			Vec:     cwv.vector,
+			ChunkID:     chunkID,
+			Dim:         len(cwv.vector),
+			Vec:         cwv.vector,
+			Model:       idx.embedder.ModelName(),
+			InputSHA256: cwv.inputHash,
 		}
 
 		if err := idx.store.InsertEmbedding(ctx, tx, embedding); err != nil {
 			return fmt.Errorf("insert embedding %d: %w", cwv.index, err)
 		}
+		addedIDs = append(addedIDs, chunkID)
+	}
+
+	// Links find their chunk by line, so they go in after the chunks
+	if err := idx.store.ReplaceLinksTx(ctx, tx, path, job.links); err != nil {
+		return fmt.Errorf("replace links: %w", err)
+	}
+
+	// Update file info (within the same transaction)
+	fileInfo := &store.FileInfo{
+		Path:          path,
+		SHA256:        job.hash,
+		MtimeUnix:     job.mtime,
+		IndexedAtUnix: time.Now().Unix(),
+	}
+	if err := idx.store.UpsertFileInfoTx(ctx, tx, fileInfo); err != nil {
+		return fmt.Errorf("upsert file info: %w", err)
+	}
 
-		// Add to ANN index
-		if err := idx.annIndex.Add(uint64(chunkID), cwv.vector); err != nil {
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+
+	// Mirror the committed state in the search index only after commit, so
+	// a failed transaction never leaves phantom or missing vectors behind.
+	for _, id := range staleIDs {
+		if err := idx.annIndex.Remove(uint64(id)); err != nil {
+			return fmt.Errorf("remove from ann index: %w", err)
+		}
+	}
+	for i, id := range addedIDs {
+		if err := idx.annIndex.Add(uint64(id), validChunks[i].vector); err != nil {
 			return fmt.Errorf("add to ann index: %w", err)
 		}
 	}
 
-	// Update file info (within the same transaction)
+	return nil
+}
+
+// renameSource returns a tracked file with the given content hash whose
+// path has disappeared from disk, or "" if there is none.
+func (idx *Indexer) renameSource(ctx context.Context, path, fileHash string) (string, error) {
+	candidates, err := idx.store.GetFilesBySHA256(ctx, fileHash)
+	if err != nil {
+		return "", fmt.Errorf("find files by hash: %w", err)
+	}
+
+	for _, c := range candidates {
+		if c.Path == path {
+			continue
+		}
+		if _, err := os.Stat(c.Path); !os.IsNotExist(err) {
+			continue // still on disk: a copy, not a rename
+		}
+		if idx.strategyFor(c.Path) != idx.strategyFor(path) {
+			continue // moved to a folder chunked differently: chunk it again
+		}
+		return c.Path, nil
+	}
+	return "", nil
+}
+
+// tryRename looks for a tracked file with the same content hash whose path
+// has disappeared from disk and, if found, moves its chunks to path.
+func (idx *Indexer) tryRename(ctx context.Context, path, fileHash string, mtime int64) (bool, error) {
+	source, err := idx.renameSource(ctx, path, fileHash)
+	if err != nil || source == "" {
+		return false, err
+	}
+
+	tx, err := idx.store.BeginTx(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := idx.store.RenameFileTx(ctx, tx, source, path); err != nil {
+		return false, err
+	}
 	fileInfo := &store.FileInfo{
 		Path:          path,
 		SHA256:        fileHash,
@@ -206,43 +497,214 @@ func (idx *Indexer) IndexFile(ctx context.Context, path string) error {
 		IndexedAtUnix: time.Now().Unix(),
 	}
 	if err := idx.store.UpsertFileInfoTx(ctx, tx, fileInfo); err != nil {
-		return fmt.Errorf("upsert file info: %w", err)
+		return false, fmt.Errorf("upsert file info: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit tx: %w", err)
 	}
+	return true, nil
+}
 
+// RemoveFile deactivates all chunks of a deleted file, drops its vectors
+// from the search index and forgets the file, so search stops returning it.
+// Removing an untracked path is a no-op.
+func (idx *Indexer) RemoveFile(ctx context.Context, path string) error {
+	tx, err := idx.store.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	staleIDs, err := idx.store.MarkChunksInactive(ctx, tx, path)
+	if err != nil {
+		return fmt.Errorf("mark chunks inactive: %w", err)
+	}
+	if err := idx.store.DeleteFileInfoTx(ctx, tx, path); err != nil {
+		return fmt.Errorf("delete file info: %w", err)
+	}
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("commit tx: %w", err)
 	}
 
+	for _, id := range staleIDs {
+		if err := idx.annIndex.Remove(uint64(id)); err != nil {
+			return fmt.Errorf("remove from ann index: %w", err)
+		}
+	}
 	return nil
 }
 
-// IndexVault processes all markdown files in the vault
+// RemoveDir removes every tracked file under dir that is no longer on
+// disk. Used when a whole folder is deleted or moved out of the vault;
+// fsnotify reports only the directory itself in that case.
+func (idx *Indexer) RemoveDir(ctx context.Context, dir string) (int, error) {
+	return idx.removeMissing(ctx, dir+string(filepath.Separator))
+}
+
+// Reconcile removes every tracked file that no longer exists on disk,
+// catching deletions that happened while the indexer was not running.
+func (idx *Indexer) Reconcile(ctx context.Context) (int, error) {
+	return idx.removeMissing(ctx, "")
+}
+
+// removeMissing removes tracked files under prefix that are gone from disk
+func (idx *Indexer) removeMissing(ctx context.Context, prefix string) (int, error) {
+	paths, err := idx.store.ListFilePaths(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list files: %w", err)
+	}
+
+	removed := 0
+	for _, p := range paths {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			continue
+		}
+		if err := idx.RemoveFile(ctx, p); err != nil {
+			return removed, fmt.Errorf("remove %s: %w", p, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// IndexVault processes all markdown files in the vault, hashing, parsing
+// and embedding up to SetConcurrency files at a time. Every write happens
+// on the calling goroutine, one transaction per file, so the store keeps a
+// single writer.
 func (idx *Indexer) IndexVault(ctx context.Context) error {
-	fileCount := 0
-	errorCount := 0
-	skippedCount := 0
-	indexedCount := 0
+	workers := idx.concurrency
+	if workers < 1 {
+		workers = 1
+	}
 
-	err := filepath.Walk(idx.vaultDir, func(path string, info os.FileInfo, err error) error {
+	if n, err := idx.backfillLinks(ctx); err != nil {
+		return fmt.Errorf("backfill links: %w", err)
+	} else if n > 0 {
+		fmt.Printf("   🔗 Extracted links from %d previously indexed notes\n", n)
+	}
+
+	// Notes indexed with other chunkers, context or document prefix are
+	// all indexed again
+	settings := map[string]string{
+		chunkerConfigKey: idx.chunkerConfig(),
+		contextConfigKey: idx.contextConfig(),
+	}
+	indexed, err := idx.store.GetActiveChunkCount(ctx)
+	if err != nil {
+		return fmt.Errorf("get active count: %w", err)
+	}
+	for key, value := range settings {
+		stored, err := idx.store.GetIndexMeta(ctx, key)
 		if err != nil {
-			return err
+			return fmt.Errorf("get %s: %w", key, err)
+		}
+		if stored == value {
+			continue
+		}
+		idx.reparse = true
+		switch {
+		case indexed == 0:
+		case key == chunkerConfigKey:
+			fmt.Printf("   ✂️  Chunking changed (%s → %s), re-chunking every note\n", stored, value)
+		case key == contextConfigKey:
+			fmt.Printf("   ✏️  Embedding context changed, re-embedding every note\n")
 		}
+	}
+	if err := idx.checkPrefixes(ctx, settings, indexed); err != nil {
+		return err
+	}
+	defer func() { idx.reparse = false }()
+
+	// Pipeline: walker → check/parse workers → embed workers → this
+	// goroutine, the only one that writes. Each stage hands over *fileJob;
+	// failures travel with the job so the writer can count them.
+	type result struct {
+		job  *fileJob
+		path string
+		err  error
+	}
+	paths := make(chan string, workers)
+	parsed := make(chan result, workers)
+	embedded := make(chan result, workers)
+
+	var walkErr error
+	go func() {
+		defer close(paths)
+		walkErr = filepath.Walk(idx.vaultDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 
-		if info.IsDir() {
-			// Skip hidden directories
-			if info.Name() != "." && info.Name()[0] == '.' {
-				return filepath.SkipDir
+			if info.IsDir() {
+				// Skip hidden directories
+				if info.Name() != "." && info.Name()[0] == '.' {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			// Only index markdown files
+			if filepath.Ext(path) != ".md" {
+				return nil
 			}
-			return nil
-		}
 
-		// Only index markdown files
-		if filepath.Ext(path) != ".md" {
+			paths <- path
 			return nil
-		}
+		})
+	}()
+
+	var parseWG sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		parseWG.Add(1)
+		go func() {
+			defer parseWG.Done()
+			for path := range paths {
+				job, err := idx.checkFile(ctx, path)
+				if err == nil && job != nil && !job.rename {
+					err = idx.parseFile(ctx, job)
+				}
+				parsed <- result{job: job, path: path, err: err}
+			}
+		}()
+	}
+	go func() {
+		parseWG.Wait()
+		close(parsed)
+	}()
+
+	var embedWG sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		embedWG.Add(1)
+		go func() {
+			defer embedWG.Done()
+			for r := range parsed {
+				if r.err == nil && r.job != nil && !r.job.rename {
+					idx.embedFile(ctx, r.job)
+				}
+				embedded <- r
+			}
+		}()
+	}
+	go func() {
+		embedWG.Wait()
+		close(embedded)
+	}()
+
+	fileCount := 0
+	errorCount := 0
+	skippedCount := 0
+	indexedCount := 0
+	reusedCount := 0
 
+	for r := range embedded {
 		fileCount++
-		relPath, _ := filepath.Rel(idx.vaultDir, path)
+		relPath, _ := filepath.Rel(idx.vaultDir, r.path)
 
 		// Show progress every 10 files
 		if fileCount%10 == 0 {
@@ -250,24 +712,224 @@ func (idx *Indexer) IndexVault(ctx context.Context) error {
 				fileCount, indexedCount, skippedCount, errorCount)
 		}
 
-		if err := idx.IndexFile(ctx, path); err != nil {
+		err := r.err
+		switch {
+		case err != nil:
+		case r.job == nil:
+			skippedCount++
+			continue
+		case r.job.rename:
+			err = idx.writeRename(ctx, r.job)
+		default:
+			err = idx.writeFile(ctx, r.job)
+		}
+		if err != nil {
 			errorCount++
 			fmt.Printf("   ❌ Error indexing %s: %v\n", relPath, err)
 			// Continue with other files
-		} else {
-			// IndexFile returns nil if file was skipped (unchanged)
-			// We could track this better, but for now count as indexed
-			indexedCount++
+			continue
+		}
+		indexedCount++
+		reusedCount += r.job.reused
+	}
+
+	// Final summary
+	fmt.Printf("   ✓ Indexing complete: %d files processed (%d indexed, %d unchanged, %d errors)\n",
+		fileCount, indexedCount, skippedCount, errorCount)
+	if reusedCount > 0 {
+		fmt.Printf("   ♻  Reused %d embeddings of unchanged chunks\n", reusedCount)
+	}
+
+	if walkErr != nil {
+		return walkErr
+	}
+
+	// Notes that failed keep their old chunks, so try them again next time
+	if idx.reparse && errorCount == 0 {
+		if err := idx.store.SetIndexMeta(ctx, settings); err != nil {
+			return fmt.Errorf("set index settings: %w", err)
+		}
+	}
+
+	// Purge files deleted while we weren't watching. This runs after the
+	// walk so notes moved in the meantime are first picked up as renames
+	// (keeping their embeddings) rather than deleted and re-embedded.
+	removed, err := idx.Reconcile(ctx)
+	if err != nil {
+		return fmt.Errorf("reconcile: %w", err)
+	}
+	if removed > 0 {
+		fmt.Printf("   🗑  Removed %d deleted files from the index\n", removed)
+	}
+
+	return nil
+}
+
+// backfillLinks extracts the links of every tracked note when the store
+// says they are missing (a database indexed before links were tracked).
+// Unchanged notes are skipped by the walk, so they would otherwise never
+// get links. Notes that fail to read are left to Reconcile.
+func (idx *Indexer) backfillLinks(ctx context.Context) (int, error) {
+	pending, err := idx.store.GetIndexMeta(ctx, store.LinksPendingKey)
+	if err != nil || pending != "1" {
+		return 0, err
+	}
+	paths, err := idx.store.ListFilePaths(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := idx.store.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	count := 0
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if err := idx.store.ReplaceLinksTx(ctx, tx, path, noteLinks(string(content))); err != nil {
+			return 0, fmt.Errorf("%s: %w", path, err)
 		}
+		count++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+	return count, idx.store.SetIndexMeta(ctx, map[string]string{store.LinksPendingKey: "0"})
+}
 
+// writeRename commits a job checkFile flagged as a rename. The source is
+// looked up again at write time, since another file in the same pass may
+// have claimed it; if so the file is indexed normally, inline.
+func (idx *Indexer) writeRename(ctx context.Context, job *fileJob) error {
+	renamed, err := idx.tryRename(ctx, job.path, job.hash, job.mtime)
+	if err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+	if renamed {
 		return nil
-	})
+	}
+	if err := idx.parseFile(ctx, job); err != nil {
+		return err
+	}
+	idx.embedFile(ctx, job)
+	return idx.writeFile(ctx, job)
+}
 
-	// Final summary
-	fmt.Printf("   ✓ Indexing complete: %d files processed (%d indexed, %d errors)\n",
-		fileCount, indexedCount, errorCount)
+// checkPrefixes compares the embedder's prefixes with those recorded for
+// the index (none for an index built before they were recorded). A new
+// document prefix changes every embedding, so it sets reparse and goes in
+// settings, saved once the vault is re-embedded; a new query prefix alone
+// is recorded for the server straight away.
+func (idx *Indexer) checkPrefixes(ctx context.Context, settings map[string]string, indexed int) error {
+	prefixes := idx.embedder.Prefixes()
+	encoded, err := json.Marshal(prefixes)
+	if err != nil {
+		return fmt.Errorf("encode prefixes: %w", err)
+	}
+	stored, err := idx.store.GetIndexMeta(ctx, store.EmbedPrefixesKey)
+	if err != nil {
+		return fmt.Errorf("get %s: %w", store.EmbedPrefixesKey, err)
+	}
+	if stored == string(encoded) {
+		return nil
+	}
+
+	var old embed.Prefixes
+	unreadable := stored != "" && json.Unmarshal([]byte(stored), &old) != nil
+	if !unreadable && old.Document == prefixes.Document {
+		if err := idx.store.SetIndexMeta(ctx, map[string]string{store.EmbedPrefixesKey: string(encoded)}); err != nil {
+			return fmt.Errorf("set %s: %w", store.EmbedPrefixesKey, err)
+		}
+		return nil
+	}
+
+	idx.reparse = true
+	settings[store.EmbedPrefixesKey] = string(encoded)
+	if indexed > 0 {
+		fmt.Printf("   ✏️  Document prefix changed (%q → %q), re-embedding every note\n", old.Document, prefixes.Document)
+	}
+	return nil
+}
+
+// embedFile fills in job.vecs. Chunks whose embedding input (document
+// prefix, context and content) the current model has already embedded reuse the stored vector,
+// so editing one paragraph of a long note costs one embedding call rather
+// than one per chunk; only the rest go to the embedder. A failed lookup
+// just means embedding everything.
+func (idx *Indexer) embedFile(ctx context.Context, job *fileJob) {
+	job.vecs = make([][]float32, len(job.toEmbed))
+	job.reused = 0
+	job.hashes = make([]string, len(job.toEmbed))
+	if len(job.toEmbed) == 0 {
+		return
+	}
+
+	hashes, prefix := job.hashes, idx.embedder.Prefixes().Document
+	for j, i := range job.toEmbed {
+		hashes[j] = chunker.ComputeContentHash(prefix + job.chunks[i].EmbedText())
+	}
+	stored, err := idx.store.GetEmbeddingsByInputHash(ctx, idx.embedder.ModelName(), hashes)
+	if err != nil {
+		fmt.Printf("  Warning: embedding lookup failed, embedding all chunks: %v\n", err)
+	}
+
+	var missing, which []int // positions in toEmbed, and their chunk indexes
+	for j, i := range job.toEmbed {
+		if vec, ok := stored[hashes[j]]; ok {
+			job.vecs[j] = vec
+			job.reused++
+			continue
+		}
+		missing = append(missing, j)
+		which = append(which, i)
+	}
 
-	return err
+	fresh := idx.embedChunks(ctx, job.chunks, which)
+	for k, j := range missing {
+		job.vecs[j] = fresh[k]
+	}
+}
+
+// embedChunks embeds the selected chunks in one batch and returns their
+// vectors in the same order. If the batch fails, each chunk is retried on
+// its own so one bad chunk costs only itself; chunks that still fail are
+// logged and left nil.
+func (idx *Indexer) embedChunks(ctx context.Context, chunks []chunker.Chunk, which []int) [][]float32 {
+	if len(which) == 0 {
+		return nil
+	}
+	texts := make([]string, len(which))
+	for j, i := range which {
+		texts[j] = chunks[i].EmbedText()
+	}
+
+	vecs, err := idx.embedder.EmbedDocuments(ctx, texts)
+	if err == nil && len(vecs) == len(texts) {
+		return vecs
+	}
+	if err != nil {
+		fmt.Printf("  Warning: batch embed failed, retrying chunks individually: %v\n", err)
+	}
+
+	vecs = make([][]float32, len(texts))
+	for j := range texts {
+		vec, err := idx.embedder.EmbedDocuments(ctx, texts[j:j+1])
+		if err == nil && len(vec) != 1 {
+			err = fmt.Errorf("got %d vectors for 1 input", len(vec))
+		}
+		if err != nil {
+			// Log but continue with other chunks
+			fmt.Printf("  Warning: embed chunk %d failed: %v\n", which[j], err)
+			continue
+		}
+		vecs[j] = vec[0]
+	}
+	return vecs
 }
 
 // computeFileHash returns SHA256 hash and mtime of a file
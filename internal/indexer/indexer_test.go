@@ -3,13 +3,18 @@ package indexer
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/sethfair/obsidx/internal/ann"
 	"github.com/sethfair/obsidx/internal/chunker"
+	"github.com/sethfair/obsidx/internal/config"
+	"github.com/sethfair/obsidx/internal/embed"
 	"github.com/sethfair/obsidx/internal/store"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -17,21 +22,70 @@ import (
 
 // fakeEmbedder records every text it is asked to embed and returns a
 // deterministic distinct vector per call.
+// It is safe for concurrent use, as IndexVault's embed workers require.
 type fakeEmbedder struct {
-	calls []string
+	mu       sync.Mutex
+	calls    []string
+	batches  int    // EmbedBatch calls
+	failAt   []int  // calls (1-based) that return an error
+	model    string // ModelName, "fake" if empty
+	prefixes embed.Prefixes
 }
 
 func (f *fakeEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.calls = append(f.calls, text)
+	for _, n := range f.failAt {
+		if n == len(f.calls) {
+			return nil, fmt.Errorf("embed failure %d", n)
+		}
+	}
 	vec := make([]float32, 8)
 	vec[0] = float32(len(f.calls)) // distinct per call
 	vec[1] = 1
 	return vec, nil
 }
+
+func (f *fakeEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	f.mu.Lock()
+	f.batches++
+	f.mu.Unlock()
+	vecs := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec, err := f.Embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		vecs[i] = vec
+	}
+	return vecs, nil
+}
+
+func (f *fakeEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	return f.Embed(ctx, f.prefixes.Query+text)
+}
+
+func (f *fakeEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	prefixed := make([]string, len(texts))
+	for i, text := range texts {
+		prefixed[i] = f.prefixes.Document + text
+	}
+	return f.EmbedBatch(ctx, prefixed)
+}
+
+func (f *fakeEmbedder) Prefixes() embed.Prefixes     { return f.prefixes }
+func (f *fakeEmbedder) SetPrefixes(p embed.Prefixes) { f.prefixes = p }
 func (f *fakeEmbedder) Dimension() int               { return 8 }
-func (f *fakeEmbedder) ModelName() string            { return "fake" }
 func (f *fakeEmbedder) Ping(_ context.Context) error { return nil }
 
+func (f *fakeEmbedder) ModelName() string {
+	if f.model == "" {
+		return "fake"
+	}
+	return f.model
+}
+
 func newTestIndexer(t *testing.T) (*Indexer, *fakeEmbedder, string, string) {
 	t.Helper()
 	dir := t.TempDir()
@@ -106,6 +160,157 @@ func TestIndexFileSkipsHeadingOnlyChunks(t *testing.T) {
 	}
 }
 
+const threeSectionNote = "## One\n\nThe first section has enough body text.\n\n" +
+	"## Two\n\nThe second section has enough body text.\n\n" +
+	"## Three\n\nThe third section has enough body text.\n"
+
+func TestIndexFileEmbedsChunksInOneBatch(t *testing.T) {
+	idx, emb, dir, dbPath := newTestIndexer(t)
+	path := writeNote(t, dir, "note.md", threeSectionNote)
+
+	if err := idx.IndexFile(context.Background(), path); err != nil {
+		t.Fatalf("IndexFile: %v", err)
+	}
+	if emb.batches != 1 {
+		t.Errorf("expected 1 batch call, got %d", emb.batches)
+	}
+	if got := len(activeChunkContents(t, dbPath, path)); got != 3 || len(emb.calls) != 3 {
+		t.Errorf("expected 3 chunks embedded and stored, got %d calls and %d chunks", len(emb.calls), got)
+	}
+}
+
+// Editing one section re-embeds only that section; the others copy their
+// stored vectors, unless the model has changed since.
+func TestIndexFileReusesEmbeddingsOfUnchangedChunks(t *testing.T) {
+	ctx := context.Background()
+	idx, emb, dir, dbPath := newTestIndexer(t)
+	path := writeNote(t, dir, "note.md", threeSectionNote)
+	if err := idx.IndexFile(ctx, path); err != nil {
+		t.Fatalf("IndexFile: %v", err)
+	}
+
+	edited := strings.Replace(threeSectionNote, "second section", "rewritten second section", 1)
+	writeNote(t, dir, "note.md", edited)
+	if err := idx.IndexFile(ctx, path); err != nil {
+		t.Fatalf("re-index: %v", err)
+	}
+	if len(emb.calls) != 4 || !strings.Contains(emb.calls[3], "rewritten") {
+		t.Errorf("expected only the edited chunk re-embedded, calls: %q", emb.calls)
+	}
+	if got := activeChunkContents(t, dbPath, path); len(got) != 3 {
+		t.Errorf("expected 3 active chunks, got %d", len(got))
+	}
+	if idx.annIndex.Size() != 3 {
+		t.Errorf("expected 3 vectors in the search index, got %d", idx.annIndex.Size())
+	}
+
+	emb.model = "other"
+	writeNote(t, dir, "note.md", threeSectionNote)
+	if err := idx.IndexFile(ctx, path); err != nil {
+		t.Fatalf("re-index with new model: %v", err)
+	}
+	if len(emb.calls) != 7 {
+		t.Errorf("a new model must re-embed every chunk, got %d calls in total", len(emb.calls))
+	}
+}
+
+// Inline tags are note tags for weighting and filtering, and each chunk
+// also records the tags written in it.
+func TestIndexFileCollectsInlineTags(t *testing.T) {
+	ctx := context.Background()
+	idx, _, dir, dbPath := newTestIndexer(t)
+	idx.SetWeightConfig(&config.WeightConfig{
+		TagWeights:    []config.TagWeight{{Tag: "project", Weight: 1.2}},
+		DefaultWeight: 1.0,
+	})
+
+	path := writeNote(t, dir, "note.md", "---\ntags: [adr]\n---\n"+
+		"## Design\nWe chose this for #project/alpha/design reasons.\n\n"+
+		"## Notes\nNothing is tagged in this section.\n```\necho #not-a-tag\n```\n")
+	if err := idx.IndexFile(ctx, path); err != nil {
+		t.Fatalf("IndexFile: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT tags, chunk_tags, category_weight FROM chunks WHERE active = 1 ORDER BY chunk_index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var tags, chunkTags string
+		var weight float32
+		if err := rows.Scan(&tags, &chunkTags, &weight); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%s %s %.1f", tags, chunkTags, weight))
+	}
+	want := []string{
+		`["adr","project/alpha/design"] ["project/alpha/design"] 1.2`,
+		`["adr","project/alpha/design"] [] 1.2`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("chunks:\n got %q\nwant %q", got, want)
+	}
+}
+
+// Front matter never reaches chunk content; selected properties are
+// embedded as context, and changing them re-embeds the note.
+func TestIndexFileEmbedsPropertyContext(t *testing.T) {
+	ctx := context.Background()
+	idx, emb, dir, dbPath := newTestIndexer(t)
+	idx.SetContextProperties([]string{"title", "aliases"})
+
+	note := "---\ntitle: Roadmap\naliases:\n  - Plan\nstatus: active\n---\n\nThe body of the roadmap note.\n"
+	path := writeNote(t, dir, "note.md", note)
+	if err := idx.IndexFile(ctx, path); err != nil {
+		t.Fatalf("IndexFile: %v", err)
+	}
+
+	got := activeChunkContents(t, dbPath, path)
+	if len(got) != 1 || got[0] != "The body of the roadmap note." {
+		t.Errorf("stored content: %q", got)
+	}
+	want := "title: Roadmap\naliases: Plan\n\nThe body of the roadmap note."
+	if len(emb.calls) != 1 || emb.calls[0] != want {
+		t.Errorf("embedded %q, want %q", emb.calls, want)
+	}
+
+	writeNote(t, dir, "note.md", strings.Replace(note, "Roadmap", "Plan for 2027", 1))
+	if err := idx.IndexFile(ctx, path); err != nil {
+		t.Fatalf("re-index: %v", err)
+	}
+	if len(emb.calls) != 2 {
+		t.Errorf("a new title must re-embed the unchanged body, got %d calls", len(emb.calls))
+	}
+}
+
+// A failed batch is retried chunk by chunk, so only the chunk that really
+// fails is dropped.
+func TestIndexFileRetriesFailedBatchPerChunk(t *testing.T) {
+	idx, emb, dir, dbPath := newTestIndexer(t)
+	emb.failAt = []int{2, 4} // fails the batch, then the second chunk's retry
+	path := writeNote(t, dir, "note.md", threeSectionNote)
+
+	if err := idx.IndexFile(context.Background(), path); err != nil {
+		t.Fatalf("IndexFile: %v", err)
+	}
+	got := activeChunkContents(t, dbPath, path)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 chunks stored, got %d: %q", len(got), got)
+	}
+	for _, c := range got {
+		if strings.Contains(c, "second") {
+			t.Errorf("chunk whose embedding failed was stored: %q", c)
+		}
+	}
+}
+
 // Regression test: a file whose new revision produces zero indexable chunks
 // must still deactivate its old chunks and record the new file hash.
 // Previously IndexFile returned before MarkChunksInactive in that path, so
@@ -163,3 +368,610 @@ func TestIndexFileEmptiedFileDeactivatesChunks(t *testing.T) {
 		t.Errorf("stale chunks still active after file emptied: %v", got)
 	}
 }
+
+// The recall server follows the chunk change log to stay in sync with the
+// indexer, so every re-index must record removes for the old chunks and
+// adds for the new ones, in that order.
+func TestIndexFileRecordsChunkChanges(t *testing.T) {
+	idx, _, dir, _ := newTestIndexer(t)
+	ctx := context.Background()
+
+	path := writeNote(t, dir, "note.md", "## Section\n\nFirst revision body, long enough to embed.\n")
+	if err := idx.IndexFile(ctx, path); err != nil {
+		t.Fatalf("initial IndexFile: %v", err)
+	}
+	first, err := idx.store.GetChangesSince(ctx, 0, 100)
+	if err != nil {
+		t.Fatalf("get changes: %v", err)
+	}
+	if len(first) != 1 || first[0].Op != store.ChangeAdd || first[0].Vec == nil {
+		t.Fatalf("expected one add with a vector after first index, got %+v", first)
+	}
+
+	writeNote(t, dir, "note.md", "## Section\n\nSecond revision body, also long enough.\n")
+	if err := idx.IndexFile(ctx, path); err != nil {
+		t.Fatalf("re-IndexFile: %v", err)
+	}
+	changes, err := idx.store.GetChangesSince(ctx, first[0].Seq, 100)
+	if err != nil {
+		t.Fatalf("get changes: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected remove+add after re-index, got %+v", changes)
+	}
+	if changes[0].Op != store.ChangeRemove || changes[0].ChunkID != first[0].ChunkID {
+		t.Errorf("first change = %+v, want remove of chunk %d", changes[0], first[0].ChunkID)
+	}
+	if changes[1].Op != store.ChangeAdd || changes[1].ChunkID <= first[0].ChunkID {
+		t.Errorf("second change = %+v, want add of a new chunk", changes[1])
+	}
+
+	latest, err := idx.store.GetLatestChangeSeq(ctx)
+	if err != nil {
+		t.Fatalf("latest seq: %v", err)
+	}
+	if latest != changes[1].Seq {
+		t.Errorf("GetLatestChangeSeq = %d, want %d", latest, changes[1].Seq)
+	}
+}
+
+// Re-indexing a note must drop its old vectors from the in-memory index,
+// not just deactivate them in SQLite — otherwise a long-running watch
+// process accumulates dead vectors that crowd out real candidates.
+func TestIndexFileReplacesVectorsInIndex(t *testing.T) {
+	idx, _, dir, _ := newTestIndexer(t)
+	ctx := context.Background()
+
+	path := writeNote(t, dir, "note.md", "## One\n\nFirst section body, long enough.\n\n## Two\n\nSecond section body, long enough.\n")
+	if err := idx.IndexFile(ctx, path); err != nil {
+		t.Fatalf("initial IndexFile: %v", err)
+	}
+	if got := idx.annIndex.Size(); got != 2 {
+		t.Fatalf("index size after first pass = %d, want 2", got)
+	}
+
+	for i := 0; i < 3; i++ {
+		writeNote(t, dir, "note.md", strings.Repeat("x", i+1)+"\n\n## One\n\nRewritten section body, long enough.\n")
+		if err := idx.IndexFile(ctx, path); err != nil {
+			t.Fatalf("re-IndexFile %d: %v", i, err)
+		}
+	}
+	if got := idx.annIndex.Size(); got != 1 {
+		t.Errorf("index size after rewrites = %d, want 1 (stale vectors kept)", got)
+	}
+}
+
+func TestRemoveFileDeactivatesChunksAndForgetsFile(t *testing.T) {
+	idx, _, dir, dbPath := newTestIndexer(t)
+	ctx := context.Background()
+
+	path := writeNote(t, dir, "note.md", "## Section\n\nBody content long enough to be embedded and stored.\n")
+	if err := idx.IndexFile(ctx, path); err != nil {
+		t.Fatalf("IndexFile: %v", err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := idx.RemoveFile(ctx, path); err != nil {
+		t.Fatalf("RemoveFile: %v", err)
+	}
+	if got := activeChunkContents(t, dbPath, path); len(got) != 0 {
+		t.Errorf("chunks still active after RemoveFile: %v", got)
+	}
+	if fi, err := idx.store.GetFileInfo(ctx, path); err != nil || fi != nil {
+		t.Errorf("file info still present after RemoveFile: %+v (err %v)", fi, err)
+	}
+	if got := idx.annIndex.Size(); got != 0 {
+		t.Errorf("index size after RemoveFile = %d, want 0", got)
+	}
+
+	// Removing an untracked path is a no-op.
+	if err := idx.RemoveFile(ctx, filepath.Join(dir, "never-indexed.md")); err != nil {
+		t.Errorf("RemoveFile on untracked path: %v", err)
+	}
+}
+
+// A note moved in Obsidian keeps its content hash; the indexer must re-key
+// its chunks to the new path without calling the embedder again.
+func TestIndexFileRenameRekeysWithoutReembedding(t *testing.T) {
+	idx, emb, dir, dbPath := newTestIndexer(t)
+	ctx := context.Background()
+
+	content := "## Section\n\nBody content long enough to be embedded and stored.\n"
+	oldPath := writeNote(t, dir, "old.md", content)
+	if err := idx.IndexFile(ctx, oldPath); err != nil {
+		t.Fatalf("IndexFile: %v", err)
+	}
+	embedCalls := len(emb.calls)
+
+	newPath := filepath.Join(dir, "moved.md")
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.IndexFile(ctx, newPath); err != nil {
+		t.Fatalf("IndexFile after rename: %v", err)
+	}
+
+	if len(emb.calls) != embedCalls {
+		t.Errorf("rename re-embedded %d chunks", len(emb.calls)-embedCalls)
+	}
+	if got := activeChunkContents(t, dbPath, oldPath); len(got) != 0 {
+		t.Errorf("chunks still under old path: %v", got)
+	}
+	if got := activeChunkContents(t, dbPath, newPath); len(got) != 1 {
+		t.Errorf("expected 1 active chunk under new path, got %v", got)
+	}
+	if fi, _ := idx.store.GetFileInfo(ctx, oldPath); fi != nil {
+		t.Error("old path still tracked after rename")
+	}
+	if fi, _ := idx.store.GetFileInfo(ctx, newPath); fi == nil {
+		t.Error("new path not tracked after rename")
+	}
+	if got := idx.annIndex.Size(); got != 1 {
+		t.Errorf("index size after rename = %d, want 1", got)
+	}
+}
+
+// A copy is not a rename: when the original still exists, the new file is
+// indexed independently.
+func TestIndexFileCopyIsIndexedSeparately(t *testing.T) {
+	idx, _, dir, dbPath := newTestIndexer(t)
+	ctx := context.Background()
+
+	content := "## Section\n\nBody content long enough to be embedded and stored.\n"
+	origPath := writeNote(t, dir, "orig.md", content)
+	copyPath := writeNote(t, dir, "copy.md", content)
+	for _, p := range []string{origPath, copyPath} {
+		if err := idx.IndexFile(ctx, p); err != nil {
+			t.Fatalf("IndexFile %s: %v", p, err)
+		}
+	}
+
+	for _, p := range []string{origPath, copyPath} {
+		if got := activeChunkContents(t, dbPath, p); len(got) != 1 {
+			t.Errorf("%s: expected 1 active chunk, got %v", filepath.Base(p), got)
+		}
+	}
+}
+
+func TestIndexVaultPurgesDeletedFiles(t *testing.T) {
+	idx, _, dir, dbPath := newTestIndexer(t)
+	ctx := context.Background()
+
+	keep := writeNote(t, dir, "keep.md", "## Keep\n\nThis note stays in the vault for good.\n")
+	gone := writeNote(t, dir, "gone.md", "## Gone\n\nThis note is deleted while nobody watches.\n")
+	if err := idx.IndexVault(ctx); err != nil {
+		t.Fatalf("IndexVault: %v", err)
+	}
+	if err := os.Remove(gone); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := idx.IndexVault(ctx); err != nil {
+		t.Fatalf("second IndexVault: %v", err)
+	}
+	if got := activeChunkContents(t, dbPath, gone); len(got) != 0 {
+		t.Errorf("deleted note still active: %v", got)
+	}
+	if fi, _ := idx.store.GetFileInfo(ctx, gone); fi != nil {
+		t.Error("deleted note still tracked")
+	}
+	if got := activeChunkContents(t, dbPath, keep); len(got) != 1 {
+		t.Errorf("kept note lost its chunk: %v", got)
+	}
+}
+
+func TestRemoveDirRemovesOnlyMissingFilesUnderDir(t *testing.T) {
+	idx, _, dir, dbPath := newTestIndexer(t)
+	ctx := context.Background()
+
+	if err := os.Mkdir(filepath.Join(dir, "projects"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	inDir := writeNote(t, dir, "projects/alpha.md", "## Alpha\n\nProject alpha note body long enough.\n")
+	outside := writeNote(t, dir, "projects-other.md", "## Other\n\nSibling note with a shared name prefix.\n")
+	for _, p := range []string{inDir, outside} {
+		if err := idx.IndexFile(ctx, p); err != nil {
+			t.Fatalf("IndexFile: %v", err)
+		}
+	}
+	if err := os.RemoveAll(filepath.Join(dir, "projects")); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := idx.RemoveDir(ctx, filepath.Join(dir, "projects"))
+	if err != nil {
+		t.Fatalf("RemoveDir: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("RemoveDir removed %d files, want 1", removed)
+	}
+	if got := activeChunkContents(t, dbPath, outside); len(got) != 1 {
+		t.Errorf("sibling note outside the directory was touched: %v", got)
+	}
+}
+
+// TestIndexVaultConcurrentMatchesSequential indexes the same vault with one
+// worker and with several; the stored chunks and search index must agree.
+// Run with -race to check the pipeline's hand-offs.
+func TestIndexVaultConcurrentMatchesSequential(t *testing.T) {
+	ctx := context.Background()
+	var results [2]map[string][]string
+	for run, workers := range []int{1, 8} {
+		idx, _, dir, dbPath := newTestIndexer(t)
+		idx.SetConcurrency(workers)
+
+		var paths []string
+		for i := 0; i < 40; i++ {
+			sub := filepath.Join(dir, fmt.Sprintf("folder%d", i%4))
+			os.MkdirAll(sub, 0o755)
+			name := filepath.Join(fmt.Sprintf("folder%d", i%4), fmt.Sprintf("note%02d.md", i))
+			paths = append(paths, writeNote(t, dir, name, fmt.Sprintf(
+				"## First %d\n\nThe first section of note %d.\n\n## Second %d\n\nThe second section of note %d.\n", i, i, i, i)))
+		}
+		if err := idx.IndexVault(ctx); err != nil {
+			t.Fatalf("IndexVault with %d workers: %v", workers, err)
+		}
+
+		results[run] = make(map[string][]string)
+		for _, p := range paths {
+			rel, _ := filepath.Rel(dir, p)
+			results[run][rel] = activeChunkContents(t, dbPath, p)
+		}
+		if got := idx.annIndex.Size(); got != 80 {
+			t.Errorf("%d workers: index size %d, want 80", workers, got)
+		}
+
+		// A second pass finds nothing to do
+		before, _ := idx.store.GetLatestChangeSeq(ctx)
+		if err := idx.IndexVault(ctx); err != nil {
+			t.Fatalf("second IndexVault: %v", err)
+		}
+		if after, _ := idx.store.GetLatestChangeSeq(ctx); after != before {
+			t.Errorf("%d workers: unchanged vault produced %d changes", workers, after-before)
+		}
+	}
+
+	for rel, want := range results[0] {
+		got := results[1][rel]
+		if strings.Join(got, "\x00") != strings.Join(want, "\x00") {
+			t.Errorf("%s: concurrent run stored %q, sequential %q", rel, got, want)
+		}
+	}
+}
+
+// TestIndexVaultPicksUpRenames checks that renames found during a
+// concurrent vault pass keep their embeddings.
+func TestIndexVaultPicksUpRenames(t *testing.T) {
+	idx, emb, dir, dbPath := newTestIndexer(t)
+	idx.SetConcurrency(4)
+	ctx := context.Background()
+
+	var olds []string
+	for i := 0; i < 5; i++ {
+		olds = append(olds, writeNote(t, dir, fmt.Sprintf("old%d.md", i),
+			fmt.Sprintf("## Section\n\nBody of note number %d, long enough to embed.\n", i)))
+	}
+	if err := idx.IndexVault(ctx); err != nil {
+		t.Fatalf("IndexVault: %v", err)
+	}
+	embedCalls := len(emb.calls)
+
+	for i, old := range olds {
+		if err := os.Rename(old, filepath.Join(dir, fmt.Sprintf("new%d.md", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := idx.IndexVault(ctx); err != nil {
+		t.Fatalf("IndexVault after renames: %v", err)
+	}
+
+	if len(emb.calls) != embedCalls {
+		t.Errorf("renames re-embedded %d chunks", len(emb.calls)-embedCalls)
+	}
+	for i := range olds {
+		if got := activeChunkContents(t, dbPath, filepath.Join(dir, fmt.Sprintf("new%d.md", i))); len(got) != 1 {
+			t.Errorf("new%d.md: expected 1 active chunk, got %v", i, got)
+		}
+	}
+	if got := idx.annIndex.Size(); got != 5 {
+		t.Errorf("index size = %d, want 5", got)
+	}
+}
+
+// resolvedTargets maps each link target from path to its resolved path
+// (relative to dir, "" if unresolved)
+func resolvedTargets(t *testing.T, idx *Indexer, dir, path string) map[string]string {
+	t.Helper()
+	links, err := idx.store.GetLinksFrom(context.Background(), path)
+	if err != nil {
+		t.Fatalf("GetLinksFrom: %v", err)
+	}
+	got := make(map[string]string)
+	for _, l := range links {
+		rel := ""
+		if l.ResolvedPath != "" {
+			rel, _ = filepath.Rel(dir, l.ResolvedPath)
+		}
+		got[l.Target] = rel
+	}
+	return got
+}
+
+func TestIndexFileKeepsLinksInSync(t *testing.T) {
+	idx, _, dir, _ := newTestIndexer(t)
+	ctx := context.Background()
+
+	source := writeNote(t, dir, "source.md", "---\nup: \"[[Hub]]\"\n---\n"+
+		"## Links\nSee [[Target#Setup|setup]] and [moved](moved.md) and ![[chart.png]].\n")
+	if err := idx.IndexFile(ctx, source); err != nil {
+		t.Fatalf("IndexFile: %v", err)
+	}
+	want := map[string]string{"Hub": "", "Target": "", "moved.md": "", "chart.png": ""}
+	if got := resolvedTargets(t, idx, dir, source); !reflect.DeepEqual(got, want) {
+		t.Errorf("before targets exist: %v", got)
+	}
+
+	links, _ := idx.store.GetLinksFrom(ctx, source)
+	if links[0].SourceChunkID != 0 || links[1].SourceChunkID == 0 || links[1].Heading != "Setup" || links[1].Alias != "setup" {
+		t.Errorf("link details: %+v", links)
+	}
+
+	// Indexing the targets resolves the dangling links
+	target := writeNote(t, dir, "target.md", "## Target\n\nThe note the source links to.\n")
+	old := writeNote(t, dir, "old.md", "## Old\n\nA note that will move to another name.\n")
+	for _, p := range []string{target, old} {
+		if err := idx.IndexFile(ctx, p); err != nil {
+			t.Fatalf("IndexFile: %v", err)
+		}
+	}
+	if got := resolvedTargets(t, idx, dir, source)["Target"]; got != "target.md" {
+		t.Errorf("Target resolved to %q", got)
+	}
+
+	// A rename resolves links written for the new name
+	moved := filepath.Join(dir, "moved.md")
+	if err := os.Rename(old, moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.IndexFile(ctx, moved); err != nil {
+		t.Fatalf("IndexFile after rename: %v", err)
+	}
+	if got := resolvedTargets(t, idx, dir, source)["moved.md"]; got != "moved.md" {
+		t.Errorf("moved.md resolved to %q", got)
+	}
+
+	// Deleting a target leaves the link dangling; deleting the source drops its links
+	if err := idx.RemoveFile(ctx, target); err != nil {
+		t.Fatal(err)
+	}
+	if got := resolvedTargets(t, idx, dir, source)["Target"]; got != "" {
+		t.Errorf("link to deleted note still resolved to %q", got)
+	}
+	if err := idx.RemoveFile(ctx, source); err != nil {
+		t.Fatal(err)
+	}
+	if got := resolvedTargets(t, idx, dir, source); len(got) != 0 {
+		t.Errorf("links of deleted note remain: %v", got)
+	}
+}
+
+// Notes indexed before links were tracked get them on the next full pass,
+// even though they are unchanged
+func TestIndexVaultBackfillsLinks(t *testing.T) {
+	idx, emb, dir, _ := newTestIndexer(t)
+	ctx := context.Background()
+
+	a := writeNote(t, dir, "a.md", "## A\n\nLinks to [[b]] from an unchanged note.\n")
+	writeNote(t, dir, "b.md", "## B\n\nThe target of the unchanged note.\n")
+	if err := idx.IndexVault(ctx); err != nil {
+		t.Fatalf("IndexVault: %v", err)
+	}
+
+	// Simulate an upgraded database: links missing, backfill pending
+	if err := idx.store.SetIndexMeta(ctx, map[string]string{store.LinksPendingKey: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := idx.store.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.store.ReplaceLinksTx(ctx, tx, a, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := len(emb.calls)
+	if err := idx.IndexVault(ctx); err != nil {
+		t.Fatalf("second IndexVault: %v", err)
+	}
+	if got := resolvedTargets(t, idx, dir, a); got["b"] != "b.md" {
+		t.Errorf("links not backfilled: %v", got)
+	}
+	if len(emb.calls) != calls {
+		t.Error("backfill re-embedded notes")
+	}
+	if pending, _ := idx.store.GetIndexMeta(ctx, store.LinksPendingKey); pending != "0" {
+		t.Errorf("backfill still pending: %q", pending)
+	}
+}
+
+// Changing the chunk options re-chunks notes whose files are unchanged,
+// reusing the embeddings of chunks that come out the same
+func TestIndexVaultRechunksWhenChunkOptionsChange(t *testing.T) {
+	ctx := context.Background()
+	idx, emb, dir, dbPath := newTestIndexer(t)
+	body := "## Plan\n\nFirst paragraph about rollover.\n\nSecond paragraph about retention.\n"
+	path := writeNote(t, dir, "note.md", body)
+	writeNote(t, dir, "short.md", "## Short\n\nOne paragraph.\n")
+
+	if err := idx.IndexVault(ctx); err != nil {
+		t.Fatalf("IndexVault: %v", err)
+	}
+	if got := activeChunkContents(t, dbPath, path); len(got) != 1 {
+		t.Fatalf("default options: %d chunks, want 1", len(got))
+	}
+	calls := len(emb.calls)
+
+	idx.SetChunkOptions(chunker.ChunkOptions{TargetTokens: 8, MaxTokens: 12})
+	if err := idx.IndexVault(ctx); err != nil {
+		t.Fatalf("IndexVault with new options: %v", err)
+	}
+	if got := activeChunkContents(t, dbPath, path); len(got) != 2 {
+		t.Errorf("new options: %d chunks %q, want 2", len(got), got)
+	}
+	if len(emb.calls) != calls+2 {
+		t.Errorf("expected the 2 new chunks embedded, got %d calls", len(emb.calls)-calls)
+	}
+	if cfg, _ := idx.store.GetIndexMeta(ctx, chunkerConfigKey); cfg != idx.chunkerConfig() {
+		t.Errorf("recorded chunk options %q", cfg)
+	}
+
+	calls = len(emb.calls)
+	if err := idx.IndexVault(ctx); err != nil {
+		t.Fatalf("third IndexVault: %v", err)
+	}
+	if len(emb.calls) != calls {
+		t.Error("unchanged options re-chunked the vault")
+	}
+}
+
+func TestIndexFileUsesFolderChunkStrategy(t *testing.T) {
+	ctx := context.Background()
+	idx, _, dir, dbPath := newTestIndexer(t)
+	if err := idx.SetChunkStrategy("journal", "note"); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.SetChunkStrategy("journal", "bogus"); err == nil {
+		t.Error("unknown strategy accepted")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "journal", "2026"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	note := "## Morning\n\nWrote the rollover doc.\n\n## Evening\n\nReviewed retention numbers.\n"
+	day := writeNote(t, dir, "journal/2026/day.md", note)
+	other := writeNote(t, dir, "journal-ideas.md", note)
+	for _, p := range []string{day, other} {
+		if err := idx.IndexFile(ctx, p); err != nil {
+			t.Fatalf("IndexFile(%s): %v", p, err)
+		}
+	}
+	if got := activeChunkContents(t, dbPath, day); len(got) != 1 {
+		t.Errorf("journal note: %d chunks, want the whole note in 1", len(got))
+	}
+	if got := activeChunkContents(t, dbPath, other); len(got) != 2 {
+		t.Errorf("other note: %d chunks, want 2 by heading", len(got))
+	}
+	if cfg := idx.chunkerConfig(); !strings.HasPrefix(cfg, "heading ") || !strings.Contains(cfg, "; journal: note ") {
+		t.Errorf("chunker config %q", cfg)
+	}
+}
+
+// A context template names the note and section in the embedded text
+// only; changing it re-embeds unchanged notes on the next IndexVault
+func TestIndexVaultEmbedsTemplateContext(t *testing.T) {
+	ctx := context.Background()
+	idx, emb, dir, dbPath := newTestIndexer(t)
+	if err := idx.SetContextTemplate("{{.Nope}}"); err == nil {
+		t.Error("template with an unknown field accepted")
+	}
+	if err := idx.SetContextTemplate(DefaultContextTemplate); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "adr"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := writeNote(t, dir, "adr/ADR-004.md",
+		"---\naliases: [Queue choice]\nproject: billing\n---\n# ADR-004\n## Decision\nWe use SQS for billing events.\n")
+
+	if err := idx.IndexVault(ctx); err != nil {
+		t.Fatalf("IndexVault: %v", err)
+	}
+	if got := activeChunkContents(t, dbPath, path); len(got) != 1 || got[0] != "## Decision\nWe use SQS for billing events." {
+		t.Errorf("stored content: %q", got)
+	}
+	want := "ADR-004 (Queue choice)\nADR-004 > Decision\n\n## Decision\nWe use SQS for billing events."
+	if len(emb.calls) != 1 || emb.calls[0] != want {
+		t.Errorf("embedded %q, want %q", emb.calls, want)
+	}
+
+	if err := idx.SetContextTemplate(`{{.Folder}}: {{.Prop "project"}}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.IndexVault(ctx); err != nil {
+		t.Fatalf("IndexVault with a new template: %v", err)
+	}
+	if want := "adr: billing\n\n## Decision\nWe use SQS for billing events."; len(emb.calls) != 2 || emb.calls[1] != want {
+		t.Errorf("embedded %q, want %q last", emb.calls, want)
+	}
+	if cfg, _ := idx.store.GetIndexMeta(ctx, contextConfigKey); cfg != idx.contextConfig() {
+		t.Errorf("recorded context config %q", cfg)
+	}
+}
+
+// Chunks are embedded behind the document prefix, recorded for the
+// server's queries; a new prefix re-embeds unchanged notes
+func TestIndexVaultEmbedsDocumentPrefix(t *testing.T) {
+	ctx := context.Background()
+	idx, emb, dir, dbPath := newTestIndexer(t)
+	emb.prefixes = embed.Prefixes{Query: "search_query: ", Document: "search_document: "}
+	path := writeNote(t, dir, "note.md", "# Note\nQueues decouple billing.\n")
+
+	if err := idx.IndexVault(ctx); err != nil {
+		t.Fatalf("IndexVault: %v", err)
+	}
+	if want := "search_document: # Note\nQueues decouple billing."; len(emb.calls) != 1 || emb.calls[0] != want {
+		t.Errorf("embedded %q, want %q", emb.calls, want)
+	}
+	if got := activeChunkContents(t, dbPath, path); len(got) != 1 || strings.HasPrefix(got[0], "search_document") {
+		t.Errorf("stored content: %q", got)
+	}
+	if got, _ := idx.store.GetIndexMeta(ctx, store.EmbedPrefixesKey); got != `{"query":"search_query: ","document":"search_document: "}` {
+		t.Errorf("recorded prefixes %q", got)
+	}
+
+	emb.prefixes = embed.Prefixes{}
+	if err := idx.IndexVault(ctx); err != nil {
+		t.Fatalf("IndexVault without prefixes: %v", err)
+	}
+	if want := "# Note\nQueues decouple billing."; len(emb.calls) != 2 || emb.calls[1] != want {
+		t.Errorf("embedded %q, want %q last", emb.calls, want)
+	}
+
+	// Back to the old prefix: its vectors are still stored
+	emb.prefixes = embed.Prefixes{Query: "search_query: ", Document: "search_document: "}
+	if err := idx.IndexVault(ctx); err != nil {
+		t.Fatalf("IndexVault with the old prefix: %v", err)
+	}
+	if len(emb.calls) != 2 {
+		t.Errorf("re-embedded %q despite stored vectors", emb.calls[2:])
+	}
+
+	// A new query prefix is only recorded: no note is written again
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	countChunks := func() (n int) {
+		if err := db.QueryRow("SELECT COUNT(*) FROM chunks").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	before := countChunks()
+	emb.prefixes.Query = "query: "
+	if err := idx.IndexVault(ctx); err != nil {
+		t.Fatalf("IndexVault with a new query prefix: %v", err)
+	}
+	if after := countChunks(); after != before {
+		t.Errorf("chunks rewritten for a query prefix change: %d rows, was %d", after, before)
+	}
+	if got, _ := idx.store.GetIndexMeta(ctx, store.EmbedPrefixesKey); got != `{"query":"query: ","document":"search_document: "}` {
+		t.Errorf("recorded prefixes %q", got)
+	}
+}
@@ -0,0 +1,128 @@
+package metadata
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/sethfair/obsidx/internal/chunker"
+)
+
+// Link types
+const (
+	LinkWiki     = "wikilink" // [[Note]]
+	LinkEmbed    = "embed"    // ![[Note]] or ![alt](image.png)
+	LinkMarkdown = "markdown" // [text](Note.md)
+)
+
+// Link is a link from a note to another note, heading or file
+type Link struct {
+	Type    string
+	Target  string // as written, without heading or alias: "Note", "folder/Note.md", "image.png"; "" links within the note
+	Heading string // after '#': a heading, or "^id" for a block reference
+	Alias   string // display text: [[Note|alias]] or [alias](Note.md)
+	Line    int    // 0-based line in the note
+}
+
+// linkPattern matches [[wikilinks]] and [markdown](links), either one
+// optionally prefixed with '!' to embed. A markdown destination may be
+// wrapped in <> to allow spaces and may carry a "title".
+var linkPattern = regexp.MustCompile(
+	`(!?)\[\[([^\[\]]+)\]\]` +
+		`|(!?)\[([^\[\]]*)\]\((<[^<>]*>|[^()\s]+)(?:\s+"[^"]*")?\)`)
+
+// ExtractLinks returns the links in a note, front matter included (Obsidian
+// resolves "[[Note]]" property values too), in order. Links inside fenced
+// code blocks and inline code are skipped, as are markdown links to URLs.
+func ExtractLinks(markdown string) []Link {
+	var (
+		links []Link
+		fence string
+	)
+	for lineNum, line := range strings.Split(markdown, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if chunker.ClosesFence(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if marker := chunker.FenceMarker(trimmed); marker != "" {
+			fence = marker
+			continue
+		}
+		if !strings.Contains(line, "[") {
+			continue
+		}
+
+		for _, m := range linkPattern.FindAllStringSubmatch(stripCodeSpans(line), -1) {
+			var link Link
+			var ok bool
+			if m[2] != "" {
+				link, ok = parseWikilink(m[1] == "!", m[2])
+			} else {
+				link, ok = parseMarkdownLink(m[3] == "!", m[4], m[5])
+			}
+			if ok {
+				link.Line = lineNum
+				links = append(links, link)
+			}
+		}
+	}
+	return links
+}
+
+// parseWikilink splits "Note#Heading|alias"
+func parseWikilink(embed bool, inner string) (Link, bool) {
+	link := Link{Type: LinkWiki}
+	if embed {
+		link.Type = LinkEmbed
+	}
+
+	target, alias, hasAlias := strings.Cut(inner, "|")
+	if hasAlias {
+		// In tables the pipe is escaped: [[Note\|alias]]
+		target = strings.TrimSuffix(target, `\`)
+		link.Alias = strings.TrimSpace(alias)
+	}
+	target, heading, _ := strings.Cut(target, "#")
+	link.Target = strings.TrimSpace(target)
+	link.Heading = strings.TrimSpace(heading)
+	return link, link.Target != "" || link.Heading != ""
+}
+
+// parseMarkdownLink reads [text](dest), skipping links with a URL scheme
+// (https:, mailto:, obsidian:, ...)
+func parseMarkdownLink(embed bool, text, dest string) (Link, bool) {
+	link := Link{Type: LinkMarkdown, Alias: strings.TrimSpace(text)}
+	if embed {
+		link.Type = LinkEmbed
+	}
+
+	dest = strings.TrimSuffix(strings.TrimPrefix(dest, "<"), ">")
+	if u, err := url.Parse(dest); err != nil || u.Scheme != "" {
+		return link, false
+	}
+	target, heading, _ := strings.Cut(dest, "#")
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped // Obsidian writes spaces as %20
+	}
+	link.Target = strings.TrimSpace(target)
+	link.Heading = strings.TrimSpace(heading)
+	return link, link.Target != "" || link.Heading != ""
+}
+
+// stripCodeSpans blanks out `inline code` so links in it are not matched
+func stripCodeSpans(line string) string {
+	if !strings.Contains(line, "`") {
+		return line
+	}
+	parts := strings.Split(line, "`")
+	for i := 1; i < len(parts); i += 2 {
+		if i == len(parts)-1 {
+			break // unclosed: not a code span
+		}
+		parts[i] = ""
+	}
+	return strings.Join(parts, " ")
+}
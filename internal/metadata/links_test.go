@@ -0,0 +1,42 @@
+package metadata
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractLinks(t *testing.T) {
+	cases := []struct {
+		name string
+		note string
+		want []Link
+	}{
+		{"wikilink", "See [[Note]].", []Link{{Type: LinkWiki, Target: "Note"}}},
+		{"heading and alias", "[[folder/Note#Setup|the setup]]",
+			[]Link{{Type: LinkWiki, Target: "folder/Note", Heading: "Setup", Alias: "the setup"}}},
+		{"block reference", "[[Note#^abc123]]", []Link{{Type: LinkWiki, Target: "Note", Heading: "^abc123"}}},
+		{"same note heading", "[[#Context]]", []Link{{Type: LinkWiki, Heading: "Context"}}},
+		{"escaped pipe in table", `| [[Note\|alias]] |`, []Link{{Type: LinkWiki, Target: "Note", Alias: "alias"}}},
+		{"embeds", "![[diagram.png]] ![chart](img/chart%201.png)", []Link{
+			{Type: LinkEmbed, Target: "diagram.png"},
+			{Type: LinkEmbed, Target: "img/chart 1.png", Alias: "chart"},
+		}},
+		{"markdown", `[ADR](../adr/ADR-002.md#Decision "title") and [site](https://example.com)`,
+			[]Link{{Type: LinkMarkdown, Target: "../adr/ADR-002.md", Heading: "Decision", Alias: "ADR"}}},
+		{"angle brackets", "[x](<My Note.md>)", []Link{{Type: LinkMarkdown, Target: "My Note.md", Alias: "x"}}},
+		{"code", "`[[Not a link]]` [[Real]]\n```\n[[Fenced]]\n```", []Link{{Type: LinkWiki, Target: "Real"}}},
+		{"info string inside fence", "```md\n```c\n[[Fenced]]\n```\n[[Real]]", []Link{{Type: LinkWiki, Target: "Real", Line: 4}}},
+		{"front matter", "---\nup: \"[[Parent]]\"\n---\nBody [[Child]]", []Link{
+			{Type: LinkWiki, Target: "Parent", Line: 1},
+			{Type: LinkWiki, Target: "Child", Line: 3},
+		}},
+		{"none", "[not a link] and [[]] and [](mailto:x@y.z)", nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ExtractLinks(tc.note); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,14 @@
 package metadata
 
 import (
+	"fmt"
 	"strings"
 	"time"
+	"unicode"
 
+	"gopkg.in/yaml.v3"
+
+	"github.com/sethfair/obsidx/internal/chunker"
 	"github.com/sethfair/obsidx/internal/config"
 )
 
@@ -14,6 +19,11 @@ type NoteMetadata struct {
 	Status       string // active, draft, superseded, deprecated
 	LastReviewed time.Time
 	Tags         []string
+
+	// Properties holds every other front matter key (aliases, title,
+	// nested maps, ...) as decoded from YAML: string, bool, int, float64,
+	// time.Time, []any or map[string]any.
+	Properties map[string]any
 }
 
 // CalculateWeight returns the retrieval weight using the provided config
@@ -26,6 +36,25 @@ func (m *NoteMetadata) CalculateWeight(weightConfig *config.WeightConfig) float3
 	return weightConfig.CalculateWeight(m.Tags, m.Status)
 }
 
+// PropertyText renders a property as plain text: a list joins its items
+// with ", ", and a missing key or a nested map gives "".
+func (m *NoteMetadata) PropertyText(key string) string {
+	switch v := m.Properties[key].(type) {
+	case map[string]any:
+		return ""
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if s := scalarString(item); s != "" {
+				items = append(items, s)
+			}
+		}
+		return strings.Join(items, ", ")
+	default:
+		return scalarString(v)
+	}
+}
+
 // IsActive returns whether this note should be included in standard retrieval
 func (m *NoteMetadata) IsActive() bool {
 	// Exclude superseded/deprecated
@@ -44,37 +73,84 @@ func (m *NoteMetadata) IsActive() bool {
 	return true
 }
 
-// ParseFrontMatter extracts YAML front matter from markdown
+// ParseFrontMatter extracts YAML front matter from markdown. The block is
+// parsed as YAML, so Obsidian's multi-line lists, quoted values and nested
+// keys all work; keys without a dedicated field land in Properties. Blocks
+// that are not valid YAML fall back to a line-based key: value reading.
 func ParseFrontMatter(markdown string) *NoteMetadata {
 	meta := &NoteMetadata{
 		Status: "active", // default
 	}
 
-	lines := strings.Split(markdown, "\n")
-	if len(lines) < 3 {
+	block, ok := frontMatterBlock(markdown)
+	if !ok {
 		return meta
 	}
 
-	// Check for YAML front matter (---...---)
-	if !strings.HasPrefix(lines[0], "---") {
+	var props map[string]any
+	if err := yaml.Unmarshal([]byte(strings.Join(block, "\n")), &props); err != nil {
+		parseSimple(meta, block)
 		return meta
 	}
 
-	endIndex := -1
-	for i := 1; i < len(lines); i++ {
-		if strings.HasPrefix(lines[i], "---") || strings.HasPrefix(lines[i], "...") {
-			endIndex = i
-			break
+	for key, value := range props {
+		switch key {
+		case "scope":
+			meta.Scope = scalarString(value)
+		case "type":
+			meta.Type = scalarString(value)
+		case "status":
+			meta.Status = normalizeStatus(scalarString(value))
+		case "last_reviewed", "lastReviewed":
+			switch v := value.(type) {
+			case time.Time:
+				meta.LastReviewed = v
+			default:
+				if t, err := time.Parse("2006-01-02", scalarString(v)); err == nil {
+					meta.LastReviewed = t
+				}
+			}
+		case "tags":
+			meta.Tags = tagValues(value)
+		default:
+			if meta.Properties == nil {
+				meta.Properties = make(map[string]any)
+			}
+			meta.Properties[key] = value
 		}
 	}
 
-	if endIndex == -1 {
-		return meta
+	// "tags: #a #b" is valid YAML, but everything after the '#' is a
+	// comment. Read the raw line for that form.
+	if len(meta.Tags) == 0 {
+		if raw, ok := rawValue(block, "tags"); ok && strings.HasPrefix(raw, "#") {
+			meta.Tags = splitTags(raw)
+		}
+	}
+
+	return meta
+}
+
+// frontMatterBlock returns the lines between the opening "---" and the
+// closing "---" or "...", if the note starts with front matter
+func frontMatterBlock(markdown string) ([]string, bool) {
+	lines := strings.Split(markdown, "\n")
+	n := chunker.FrontMatterLines(lines)
+	if n == 0 {
+		return nil, false
+	}
+	block := lines[1 : n-1]
+	for i := range block {
+		block[i] = strings.TrimRight(block[i], "\r")
 	}
+	return block, true
+}
 
-	// Parse YAML-like key: value pairs (simple parser)
-	for i := 1; i < endIndex; i++ {
-		line := strings.TrimSpace(lines[i])
+// parseSimple reads top-level key: value lines, for front matter that is
+// not valid YAML (e.g. "tags: [#a, #b]", where '#' starts a comment)
+func parseSimple(meta *NoteMetadata, block []string) {
+	for _, line := range block {
+		line = strings.TrimSpace(line)
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
@@ -102,28 +178,165 @@ func ParseFrontMatter(markdown string) *NoteMetadata {
 				meta.LastReviewed = t
 			}
 		case "tags":
-			// Support multiple tag formats:
-			// 1. Inline with hashtags: tags: #permanent-note #customer-research
-			// 2. Array format: tags: [permanent-note, customer-research]
-			// 3. Array with hashtags: tags: [#permanent-note, #customer-research]
-
-			value = strings.Trim(value, "[]")
-
-			// Split on common separators (space, comma)
-			for _, tag := range strings.FieldsFunc(value, func(r rune) bool {
-				return r == ',' || r == ' ' || r == '\t'
-			}) {
-				tag = strings.TrimSpace(tag)
-				// Remove # prefix if present (normalize to no prefix)
-				tag = strings.TrimPrefix(tag, "#")
-				if tag != "" {
-					meta.Tags = append(meta.Tags, tag)
-				}
+			meta.Tags = splitTags(value)
+		default:
+			if value == "" {
+				continue // likely the start of a block we cannot read
 			}
+			if meta.Properties == nil {
+				meta.Properties = make(map[string]any)
+			}
+			meta.Properties[key] = value
 		}
 	}
+}
 
-	return meta
+// rawValue returns the text after "key:" on the key's top-level line
+func rawValue(block []string, key string) (string, bool) {
+	for _, line := range block {
+		if rest, ok := strings.CutPrefix(line, key+":"); ok {
+			return strings.TrimSpace(rest), true
+		}
+	}
+	return "", false
+}
+
+// tagValues normalizes a YAML tags value: a list, or a string of tags
+func tagValues(value any) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		var tags []string
+		for _, item := range v {
+			tags = append(tags, splitTags(scalarString(item))...)
+		}
+		return tags
+	default:
+		return splitTags(scalarString(v))
+	}
+}
+
+// splitTags applies the tag normalization rules to one value. Supported
+// formats:
+// 1. Inline with hashtags: #permanent-note #customer-research
+// 2. Array format: [permanent-note, customer-research]
+// 3. Array with hashtags: [#permanent-note, #customer-research]
+func splitTags(value string) []string {
+	value = strings.Trim(value, "[]")
+
+	// Split on common separators (space, comma)
+	var tags []string
+	for _, tag := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	}) {
+		tag = strings.TrimSpace(tag)
+		tag = strings.Trim(tag, `"'`)
+		// Remove # prefix if present (normalize to no prefix)
+		tag = strings.TrimPrefix(tag, "#")
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// InlineTags returns the #tags written in markdown body text, in order of
+// first appearance without the '#' and without duplicates (compared
+// case-insensitively, as Obsidian does). Headings, fenced code blocks and
+// inline code spans are skipped. A tag starts after whitespace or at the
+// start of a line, may nest with '/', and must not be all digits ("#123").
+func InlineTags(body string) []string {
+	var (
+		tags  []string
+		seen  = make(map[string]bool)
+		fence string
+	)
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if chunker.ClosesFence(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if marker := chunker.FenceMarker(trimmed); marker != "" {
+			fence = marker
+			continue
+		}
+		if chunker.HeadingLevel(trimmed) > 0 {
+			continue
+		}
+		for _, tag := range lineTags(trimmed) {
+			if key := strings.ToLower(tag); !seen[key] {
+				seen[key] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// lineTags scans one line for tags, outside `code spans`
+func lineTags(line string) []string {
+	var tags []string
+	inCode := false
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '`' {
+			inCode = !inCode
+			continue
+		}
+		if inCode || r != '#' || (i > 0 && !unicode.IsSpace(runes[i-1])) {
+			continue
+		}
+		j := i + 1
+		for j < len(runes) && isTagRune(runes[j]) {
+			j++
+		}
+		tag := strings.Trim(string(runes[i+1:j]), "/")
+		if strings.TrimFunc(tag, unicode.IsDigit) != "" {
+			tags = append(tags, tag)
+		}
+		i = j - 1
+	}
+	return tags
+}
+
+func isTagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '/'
+}
+
+// MergeTags appends the tags of extra that tags does not already have,
+// compared case-insensitively
+func MergeTags(tags, extra []string) []string {
+	tags = tags[:len(tags):len(tags)] // never write into the caller's array
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		seen[strings.ToLower(tag)] = true
+	}
+	for _, tag := range extra {
+		if key := strings.ToLower(tag); !seen[key] {
+			seen[key] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// scalarString renders a YAML scalar as the text the note author wrote
+func scalarString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case time.Time:
+		return v.Format("2006-01-02")
+	default:
+		return fmt.Sprint(v)
+	}
 }
 
 func normalizeStatus(status string) string {
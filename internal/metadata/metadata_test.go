@@ -0,0 +1,154 @@
+package metadata
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFrontMatterTags(t *testing.T) {
+	cases := []struct {
+		name  string
+		front string
+		want  []string
+	}{
+		{"block list", "tags:\n  - permanent-note\n  - customer-research", []string{"permanent-note", "customer-research"}},
+		{"block list with hashes", "tags:\n  - \"#permanent-note\"\n  - '#vision'", []string{"permanent-note", "vision"}},
+		{"flow list", "tags: [permanent-note, customer-research]", []string{"permanent-note", "customer-research"}},
+		{"flow list with hashes", "tags: [#permanent-note, #customer-research]", []string{"permanent-note", "customer-research"}},
+		{"inline hashtags", "tags: #permanent-note #customer-research", []string{"permanent-note", "customer-research"}},
+		{"comma string", "tags: permanent-note, vision", []string{"permanent-note", "vision"}},
+		{"single", "tags: vision", []string{"vision"}},
+		{"nested tag", "tags:\n  - project/alpha", []string{"project/alpha"}},
+		{"number", "tags: [2024]", []string{"2024"}},
+		{"empty", "tags:", nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := ParseFrontMatter("---\n" + tc.front + "\n---\nBody\n")
+			if !reflect.DeepEqual(meta.Tags, tc.want) {
+				t.Errorf("tags: got %q, want %q", meta.Tags, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseFrontMatterFields(t *testing.T) {
+	note := "---\n" +
+		"title: \"Decision: use SQLite\"\n" +
+		"aliases:\n  - SQLite ADR\n  - ADR-002\n" +
+		"status: WIP\n" +
+		"scope: obsidx\n" +
+		"type: decision\n" +
+		"last_reviewed: 2026-07-23\n" +
+		"review:\n  owner: sam\n  every: 90\n" +
+		"draft: true\n" +
+		"tags:\n  - adr\n" +
+		"---\n# Body\n"
+
+	meta := ParseFrontMatter(note)
+	if meta.Status != "draft" || meta.Scope != "obsidx" || meta.Type != "decision" {
+		t.Errorf("fields: status %q, scope %q, type %q", meta.Status, meta.Scope, meta.Type)
+	}
+	if want := time.Date(2026, 7, 23, 0, 0, 0, 0, time.UTC); !meta.LastReviewed.Equal(want) {
+		t.Errorf("last_reviewed: got %v, want %v", meta.LastReviewed, want)
+	}
+
+	wantProps := map[string]any{
+		"title":   "Decision: use SQLite",
+		"aliases": []any{"SQLite ADR", "ADR-002"},
+		"review":  map[string]any{"owner": "sam", "every": 90},
+		"draft":   true,
+	}
+	if !reflect.DeepEqual(meta.Properties, wantProps) {
+		t.Errorf("properties:\n got %#v\nwant %#v", meta.Properties, wantProps)
+	}
+}
+
+func TestParseFrontMatterFallsBackOnInvalidYAML(t *testing.T) {
+	// Unquoted colon in a value is a YAML error; the line reader still
+	// recovers the known keys.
+	meta := ParseFrontMatter("---\ntitle: Decision: use SQLite\nstatus: deprecated\ntags: [a, #b]\n---\n")
+	if meta.Status != "deprecated" {
+		t.Errorf("status: got %q", meta.Status)
+	}
+	if !reflect.DeepEqual(meta.Tags, []string{"a", "b"}) {
+		t.Errorf("tags: got %q", meta.Tags)
+	}
+	if meta.Properties["title"] != "Decision: use SQLite" {
+		t.Errorf("title: got %#v", meta.Properties["title"])
+	}
+}
+
+func TestParseFrontMatterWithoutBlock(t *testing.T) {
+	for _, note := range []string{
+		"# Just a note\n\nNo front matter.\n",
+		"---\ntags: [a]\nno closing delimiter\n",
+		"",
+	} {
+		meta := ParseFrontMatter(note)
+		if meta.Status != "active" || meta.Tags != nil || meta.Properties != nil {
+			t.Errorf("%q: got %+v, want defaults", note, meta)
+		}
+	}
+
+	// CRLF line endings
+	meta := ParseFrontMatter("---\r\nstatus: draft\r\ntags:\r\n  - a\r\n---\r\nBody\r\n")
+	if meta.Status != "draft" || !reflect.DeepEqual(meta.Tags, []string{"a"}) {
+		t.Errorf("CRLF: got %+v", meta)
+	}
+}
+
+func TestPropertyText(t *testing.T) {
+	meta := ParseFrontMatter("---\ntitle: Roadmap\naliases: [Plan, \"Q3: goals\"]\nreview:\n  owner: sam\nyear: 2026\n---\n")
+	for key, want := range map[string]string{
+		"title":   "Roadmap",
+		"aliases": "Plan, Q3: goals",
+		"review":  "",
+		"year":    "2026",
+		"missing": "",
+	} {
+		if got := meta.PropertyText(key); got != want {
+			t.Errorf("PropertyText(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestInlineTags(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"simple", "Some text #idea here", []string{"idea"}},
+		{"tag line", "#permanent-note #writerflow", []string{"permanent-note", "writerflow"}},
+		{"nested", "See #project/alpha/design.", []string{"project/alpha/design"}},
+		{"trailing punctuation", "(#todo), #done!", []string{"done"}},
+		{"heading", "## Heading #notatag\nbody #yes", []string{"yes"}},
+		{"numbers only", "issue #123 and #2024-plan", []string{"2024-plan"}},
+		{"mid-word", "url.com/#anchor and C#", nil},
+		{"inline code", "run `git log #main` then #deploy", []string{"deploy"}},
+		{"fenced code", "```sh\n# comment\necho #x\n```\n~~~\n#y\n~~~\nafter #z", []string{"z"}},
+		{"info string inside fence", "```md\n```c\n#include <stdio.h>\n```\n#after", []string{"after"}},
+		{"duplicates", "#Idea and #idea", []string{"Idea"}},
+		{"unicode", "#café #日本", []string{"café", "日本"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := InlineTags(tc.body); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	front := []string{"adr", "Project/Alpha"}
+	got := MergeTags(front[:1], []string{"ADR", "idea", "idea"})
+	if !reflect.DeepEqual(got, []string{"adr", "idea"}) {
+		t.Errorf("got %q", got)
+	}
+	if front[1] != "Project/Alpha" {
+		t.Error("MergeTags overwrote the caller's array")
+	}
+}
@@ -3,14 +3,26 @@ package rank
 import (
 	"container/heap"
 	"math"
+	"sort"
 
+	"github.com/sethfair/obsidx/internal/ann"
 	"github.com/sethfair/obsidx/internal/store"
 )
 
 // Result represents a ranked search result
 type Result struct {
-	Chunk store.ChunkWithEmbedding
-	Score float32 // higher is better
+	Chunk   store.ChunkWithEmbedding
+	Score   float32 // higher is better
+	Explain Explanation
+}
+
+// Explanation breaks a result's score into its parts:
+// Score = Base × CategoryWeight + Graph
+type Explanation struct {
+	Base           float32  `json:"base"` // cosine similarity or fused RRF score
+	CategoryWeight float32  `json:"category_weight"`
+	Graph          float32  `json:"graph,omitempty"`        // link-proximity boost (GraphRerank)
+	LinkedNotes    []string `json:"linked_notes,omitempty"` // top notes linked to or from this one
 }
 
 // CosineSimilarity computes cosine similarity between two vectors
@@ -50,8 +62,9 @@ func RerankCosine(queryVec []float32, chunks []store.ChunkWithEmbedding, topN in
 		weightedScore := baseSimilarity * chunk.CategoryWeight
 
 		scores[i] = Result{
-			Chunk: chunk,
-			Score: weightedScore,
+			Chunk:   chunk,
+			Score:   weightedScore,
+			Explain: Explanation{Base: baseSimilarity, CategoryWeight: chunk.CategoryWeight},
 		}
 	}
 
@@ -81,6 +94,191 @@ func RerankCosine(queryVec []float32, chunks []store.ChunkWithEmbedding, topN in
 	return results
 }
 
+// DefaultRRFK is the standard reciprocal rank fusion damping constant
+const DefaultRRFK = 60
+
+// Fused is a candidate scored by reciprocal rank fusion
+type Fused struct {
+	ID    uint64
+	Score float32
+}
+
+// FuseRRF merges ranked ID lists (best first) with weighted reciprocal rank
+// fusion: score(id) = Σ weight_i / (k + rank_i), rank starting at 1. Scores
+// are divided by the best achievable score (Σ weight_i / (k + 1)), so an ID
+// ranked first in every list scores 1.0 — keeping fused scores on roughly
+// the same [0, 1] scale as cosine similarity. Ties break by ID ascending.
+func FuseRRF(lists [][]uint64, weights []float32, k int) []Fused {
+	if k <= 0 {
+		k = DefaultRRFK
+	}
+
+	var maxScore float32
+	scores := make(map[uint64]float32)
+	for i, list := range lists {
+		w := float32(1)
+		if i < len(weights) {
+			w = weights[i]
+		}
+		if w <= 0 {
+			continue
+		}
+		maxScore += w / float32(k+1)
+		for r, id := range list {
+			scores[id] += w / float32(k+r+1)
+		}
+	}
+
+	fused := make([]Fused, 0, len(scores))
+	for id, score := range scores {
+		fused = append(fused, Fused{ID: id, Score: score / maxScore})
+	}
+	sort.Slice(fused, func(i, j int) bool {
+		if fused[i].Score != fused[j].Score {
+			return fused[i].Score > fused[j].Score
+		}
+		return fused[i].ID < fused[j].ID
+	})
+	return fused
+}
+
+// RerankFused scores chunks by their fused score times category weight and
+// returns the top N. Chunks missing from fused are dropped.
+func RerankFused(fused []Fused, chunks []store.ChunkWithEmbedding, topN int) []Result {
+	byID := make(map[uint64]float32, len(fused))
+	for _, f := range fused {
+		byID[f.ID] = f.Score
+	}
+	return rerankByScore(byID, chunks, topN)
+}
+
+// RerankHits ranks chunks by the cosine similarity the index returned for
+// them, times category weight — the same scores as RerankCosine without
+// needing chunk vectors. Chunks missing from hits are dropped.
+func RerankHits(hits []ann.Hit, chunks []store.ChunkWithEmbedding, topN int) []Result {
+	byID := make(map[uint64]float32, len(hits))
+	for _, h := range hits {
+		byID[h.ID] = h.Sim
+	}
+	return rerankByScore(byID, chunks, topN)
+}
+
+// rerankByScore weights each chunk's base score and returns the top N,
+// ties broken by chunk ID.
+func rerankByScore(byID map[uint64]float32, chunks []store.ChunkWithEmbedding, topN int) []Result {
+	scores := make([]Result, 0, len(chunks))
+	for _, chunk := range chunks {
+		score, ok := byID[uint64(chunk.ID)]
+		if !ok {
+			continue
+		}
+		scores = append(scores, Result{
+			Chunk:   chunk,
+			Score:   score * chunk.CategoryWeight,
+			Explain: Explanation{Base: score, CategoryWeight: chunk.CategoryWeight},
+		})
+	}
+
+	sortResults(scores)
+	if len(scores) > topN {
+		scores = scores[:topN]
+	}
+	return scores
+}
+
+// sortResults orders results by score, best first, ties broken by chunk ID
+func sortResults(results []Result) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Chunk.ID < results[j].Chunk.ID
+	})
+}
+
+// DefaultGraphSeeds is how many of the best notes GraphRerank boosts the
+// neighbors of by default
+const DefaultGraphSeeds = 5
+
+// SeedNotes returns the paths of the notes holding the best results, best
+// first and at most n. results must be sorted best first.
+func SeedNotes(results []Result, n int) []string {
+	var seeds []string
+	seen := make(map[string]bool)
+	for _, r := range results {
+		if len(seeds) == n {
+			break
+		}
+		if !seen[r.Chunk.Path] {
+			seen[r.Chunk.Path] = true
+			seeds = append(seeds, r.Chunk.Path)
+		}
+	}
+	return seeds
+}
+
+// GraphRerank boosts results whose note links to or from one of the seed
+// notes (the notes of the best results, see SeedNotes) and returns the top
+// N. A result linked to seeds S gains
+//
+//	weight × Σ score(s ∈ S) / Σ score(every seed)
+//
+// where a seed's score is that of its best result, so the boost is at
+// most weight and grows with how strongly the linked notes matched. A note
+// never boosts itself. neighbors maps each seed to the notes it links to
+// or from (store.GetNoteNeighbors). results must be sorted best first.
+func GraphRerank(results []Result, neighbors map[string][]string, weight float32, seeds, topN int) []Result {
+	seedPaths := SeedNotes(results, seeds)
+
+	seedScore := make(map[string]float32, len(seedPaths))
+	var total float32
+	for _, r := range results {
+		if _, ok := seedScore[r.Chunk.Path]; ok {
+			continue
+		}
+		for _, p := range seedPaths {
+			if p == r.Chunk.Path {
+				seedScore[p] = r.Score
+				total += r.Score
+			}
+		}
+	}
+
+	if weight > 0 && total > 0 {
+		// linked[note] lists the seeds it links to or from
+		linked := make(map[string][]string)
+		for _, seed := range seedPaths {
+			for _, note := range neighbors[seed] {
+				if note != seed {
+					linked[note] = append(linked[note], seed)
+				}
+			}
+		}
+
+		for i := range results {
+			r := &results[i]
+			var sum float32
+			for _, seed := range linked[r.Chunk.Path] {
+				if seedScore[seed] > 0 {
+					sum += seedScore[seed]
+				}
+			}
+			if sum == 0 {
+				continue
+			}
+			r.Explain.Graph = weight * sum / total
+			r.Explain.LinkedNotes = linked[r.Chunk.Path]
+			r.Score += r.Explain.Graph
+		}
+		sortResults(results)
+	}
+
+	if len(results) > topN {
+		results = results[:topN]
+	}
+	return results
+}
+
 // resultHeap is a min-heap of Results by score
 type resultHeap []Result
 
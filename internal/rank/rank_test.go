@@ -0,0 +1,188 @@
+package rank
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/sethfair/obsidx/internal/ann"
+	"github.com/sethfair/obsidx/internal/store"
+)
+
+func TestFuseRRF(t *testing.T) {
+	vector := []uint64{1, 2, 3}
+	keyword := []uint64{3, 4}
+
+	fused := FuseRRF([][]uint64{vector, keyword}, []float32{1, 1}, 60)
+	if len(fused) != 4 {
+		t.Fatalf("expected 4 fused ids, got %+v", fused)
+	}
+	// 3 appears in both lists and must outrank everything found only once.
+	if fused[0].ID != 3 {
+		t.Errorf("expected id 3 (in both lists) first, got %+v", fused)
+	}
+	// 1 (vector rank 1) beats 4 (keyword rank 2) at equal weights.
+	pos := map[uint64]int{}
+	for i, f := range fused {
+		pos[f.ID] = i
+	}
+	if pos[1] > pos[4] {
+		t.Errorf("id 1 should outrank id 4: %+v", fused)
+	}
+	for i := 1; i < len(fused); i++ {
+		if fused[i].Score > fused[i-1].Score {
+			t.Errorf("fused results not sorted: %+v", fused)
+		}
+	}
+}
+
+func TestFuseRRFNormalizesAndWeights(t *testing.T) {
+	// An ID ranked first in every list scores exactly 1.
+	fused := FuseRRF([][]uint64{{7}, {7}}, []float32{1, 2}, 60)
+	if len(fused) != 1 || fused[0].Score < 0.9999 || fused[0].Score > 1.0001 {
+		t.Errorf("expected normalized score 1, got %+v", fused)
+	}
+
+	// Keyword-heavy weighting flips the winner.
+	fused = FuseRRF([][]uint64{{1, 2}, {2, 1}}, []float32{1, 3}, 60)
+	if fused[0].ID != 2 {
+		t.Errorf("expected keyword-weighted id 2 first, got %+v", fused)
+	}
+
+	// Zero weight ignores a list entirely.
+	fused = FuseRRF([][]uint64{{1}, {2}}, []float32{1, 0}, 60)
+	if len(fused) != 1 || fused[0].ID != 1 {
+		t.Errorf("zero-weight list should be ignored, got %+v", fused)
+	}
+}
+
+func TestRerankFusedAppliesCategoryWeight(t *testing.T) {
+	fused := []Fused{{ID: 1, Score: 1.0}, {ID: 2, Score: 0.9}}
+	chunks := []store.ChunkWithEmbedding{
+		{Chunk: store.Chunk{ID: 1, CategoryWeight: 0.6}}, // archived
+		{Chunk: store.Chunk{ID: 2, CategoryWeight: 1.3}}, // permanent note
+		{Chunk: store.Chunk{ID: 3, CategoryWeight: 1.0}}, // not a candidate
+	}
+
+	results := RerankFused(fused, chunks, 10)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Chunk.ID != 2 {
+		t.Errorf("expected weighted chunk 2 first, got %d", results[0].Chunk.ID)
+	}
+}
+
+// TestRerankHitsMatchesRerankCosine checks that ranking by the index's
+// similarity scores gives the same results as recomputing cosine from the
+// stored vectors.
+func TestRerankHitsMatchesRerankCosine(t *testing.T) {
+	const dim = 16
+	rng := rand.New(rand.NewSource(3))
+	idx := ann.NewBruteForce(dim)
+	weights := []float32{1.0, 1.3, 0.6, 0.9}
+
+	var chunks []store.ChunkWithEmbedding
+	for i := 1; i <= 200; i++ {
+		vec := make([]float32, dim)
+		for j := range vec {
+			vec[j] = rng.Float32()*2 - 1
+		}
+		if err := idx.Add(uint64(i), vec); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+		chunks = append(chunks, store.ChunkWithEmbedding{
+			Chunk: store.Chunk{ID: int64(i), CategoryWeight: weights[i%len(weights)]},
+			Vec:   vec,
+		})
+	}
+
+	query := make([]float32, dim)
+	for j := range query {
+		query[j] = rng.Float32()*2 - 1
+	}
+	// Every vector is a candidate, so both rerankers see the same set
+	hits, err := idx.SearchWithScores(query, len(chunks), nil)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+
+	got := RerankHits(hits, chunks, 10)
+	want := RerankCosine(query, chunks, 10)
+	if len(got) != len(want) {
+		t.Fatalf("got %d results, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Chunk.ID != want[i].Chunk.ID {
+			t.Errorf("rank %d: got chunk %d, want %d", i, got[i].Chunk.ID, want[i].Chunk.ID)
+		}
+		if d := got[i].Score - want[i].Score; d > 1e-5 || d < -1e-5 {
+			t.Errorf("rank %d: got score %f, want %f", i, got[i].Score, want[i].Score)
+		}
+	}
+}
+
+func TestGraphRerankBoostsNeighborsOfTopNotes(t *testing.T) {
+	result := func(id int64, path string, score float32) Result {
+		return Result{
+			Chunk:   store.ChunkWithEmbedding{Chunk: store.Chunk{ID: id, Path: path}},
+			Score:   score,
+			Explain: Explanation{Base: score, CategoryWeight: 1},
+		}
+	}
+	results := []Result{
+		result(1, "adr.md", 0.90),
+		result(2, "adr.md", 0.85),
+		result(3, "plan.md", 0.60),
+		result(4, "linked.md", 0.58),
+		result(5, "other.md", 0.59),
+	}
+	neighbors := map[string][]string{
+		"adr.md":  {"linked.md", "adr.md"}, // a self-link never boosts
+		"plan.md": {"linked.md", "adr.md"},
+	}
+
+	got := GraphRerank(results, neighbors, 0.1, 2, 4)
+	ids := make([]int64, len(got))
+	for i, r := range got {
+		ids[i] = r.Chunk.ID
+	}
+	// Seeds are adr.md (0.90) and plan.md (0.60). linked.md is linked to
+	// both: 0.58 + 0.1 × 1.5/1.5. adr.md and plan.md link to each other.
+	if want := []int64{1, 2, 4, 3}; !equalIDs(ids, want) {
+		t.Fatalf("order %v, want %v", ids, want)
+	}
+
+	linked := got[2].Explain
+	if d := linked.Graph - 0.1; d > 1e-6 || d < -1e-6 {
+		t.Errorf("linked.md boost %f, want 0.1", linked.Graph)
+	}
+	if len(linked.LinkedNotes) != 2 {
+		t.Errorf("linked notes %v", linked.LinkedNotes)
+	}
+	if d := got[0].Explain.Graph - 0.1*0.6/1.5; d > 1e-6 || d < -1e-6 {
+		t.Errorf("adr.md boost from plan.md %f", got[0].Explain.Graph)
+	}
+	for _, r := range got {
+		if want := r.Explain.Base*r.Explain.CategoryWeight + r.Explain.Graph; r.Score != want {
+			t.Errorf("chunk %d: score %f does not match its explanation %+v", r.Chunk.ID, r.Score, r.Explain)
+		}
+	}
+
+	// Weight 0 leaves scores alone
+	results = []Result{result(1, "a.md", 0.9), result(2, "b.md", 0.8)}
+	if got := GraphRerank(results, map[string][]string{"a.md": {"b.md"}}, 0, 5, 10); got[1].Score != 0.8 {
+		t.Errorf("weight 0 changed scores: %+v", got)
+	}
+}
+
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
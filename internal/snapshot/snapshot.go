@@ -0,0 +1,195 @@
+// Package snapshot loads the in-memory search index from an on-disk vector
+// snapshot, falling back to streaming embeddings out of SQLite when the
+// snapshot is missing, stale or corrupt. SQLite stays authoritative: a
+// snapshot is only a cache of the active embeddings as of a change seq,
+// brought current by replaying the change log.
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/sethfair/obsidx/internal/ann"
+	"github.com/sethfair/obsidx/internal/store"
+)
+
+// FileName is the default snapshot file name, kept beside the database
+const FileName = "vectors.snap"
+
+// Path resolves a --snapshot flag value: "" means FileName beside dbPath,
+// "off" disables snapshots (returns "").
+func Path(flagValue, dbPath string) string {
+	switch flagValue {
+	case "off":
+		return ""
+	case "":
+		return filepath.Join(filepath.Dir(dbPath), FileName)
+	default:
+		return flagValue
+	}
+}
+
+// Load returns an index holding the store's active embeddings and the
+// change seq it is current to. It uses the snapshot at path when it matches
+// dim and model, then replays later changes; otherwise it streams every
+// embedding from SQLite. An empty path skips the snapshot. opts configures
+// the returned index's storage.
+func Load(ctx context.Context, st *store.SQLite, path string, dim int, model string, opts ann.Options) (*ann.BruteForce, int64, error) {
+	if path != "" {
+		idx, seq, err := loadSnapshot(ctx, st, path, dim, model, opts)
+		if err == nil {
+			return idx, seq, nil
+		}
+		log.Printf("Vector snapshot unusable (%v), loading from database", err)
+	}
+
+	// Read the seq before streaming: anything committed during the stream
+	// is replayed by the caller's next sync, and replay is idempotent.
+	seq, err := st.GetLatestChangeSeq(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get change seq: %w", err)
+	}
+	idx := ann.NewBruteForceWithOptions(dim, opts)
+	if _, err := Stream(ctx, st, idx); err != nil {
+		return nil, 0, err
+	}
+	return idx, seq, nil
+}
+
+func loadSnapshot(ctx context.Context, st *store.SQLite, path string, dim int, model string, opts ann.Options) (*ann.BruteForce, int64, error) {
+	idx, hdr, err := ann.LoadBruteForceWithOptions(path, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	latest, err := st.GetLatestChangeSeq(ctx)
+	if err != nil {
+		idx.Close()
+		return nil, 0, fmt.Errorf("get change seq: %w", err)
+	}
+	switch {
+	case hdr.Dim != dim:
+		err = fmt.Errorf("dimension %d, want %d", hdr.Dim, dim)
+	case hdr.Model != model:
+		err = fmt.Errorf("model %q, want %q", hdr.Model, model)
+	case hdr.ChangeSeq > latest:
+		// The database is older than the snapshot (restored or recreated)
+		err = fmt.Errorf("change seq %d is ahead of database (%d)", hdr.ChangeSeq, latest)
+	}
+	if err != nil {
+		idx.Close()
+		return nil, 0, fmt.Errorf("stale: %w", err)
+	}
+
+	stats, err := Replay(ctx, st, idx, hdr.ChangeSeq)
+	if err != nil {
+		idx.Close()
+		return nil, 0, fmt.Errorf("replay changes: %w", err)
+	}
+	log.Printf("Loaded %d vectors from snapshot (seq %d), replayed +%d / -%d changes",
+		hdr.Count, hdr.ChangeSeq, stats.Added, stats.Removed)
+	return idx, stats.LastSeq, nil
+}
+
+// Stream adds every active embedding in the store to idx and returns how
+// many were added. Unloadable rows (e.g. zero-norm vectors in an old
+// database) are logged and skipped rather than failing the load.
+func Stream(ctx context.Context, st *store.SQLite, idx ann.Index) (int, error) {
+	rows, err := st.StreamActiveEmbeddings(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("stream embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var id uint64
+		var vecBlob []byte
+		if err := rows.Scan(&id, &vecBlob); err != nil {
+			return count, fmt.Errorf("scan row: %w", err)
+		}
+
+		vec, err := store.BytesToFloat32(vecBlob)
+		if err != nil {
+			return count, fmt.Errorf("decode vec for chunk %d: %w", id, err)
+		}
+
+		if err := idx.Add(id, vec); err != nil {
+			log.Printf("Skipping chunk %d: %v", id, err)
+			continue
+		}
+		count++
+
+		if count%10000 == 0 {
+			log.Printf("Loading vectors: %d...", count)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return count, fmt.Errorf("iteration error: %w", err)
+	}
+	return count, nil
+}
+
+// ReplayStats summarizes a Replay
+type ReplayStats struct {
+	LastSeq int64 // seq of the last change applied (afterSeq if none)
+	Added   int
+	Removed int
+}
+
+// Replay applies every change recorded after afterSeq to idx, in log
+// order: new chunks are upserted and deactivated chunks removed. Replaying
+// a change twice is harmless.
+func Replay(ctx context.Context, st *store.SQLite, idx ann.Index, afterSeq int64) (ReplayStats, error) {
+	const batchSize = 1000
+
+	stats := ReplayStats{LastSeq: afterSeq}
+	for {
+		changes, err := st.GetChangesSince(ctx, stats.LastSeq, batchSize)
+		if err != nil {
+			return stats, fmt.Errorf("get changes: %w", err)
+		}
+
+		for _, c := range changes {
+			id := uint64(c.ChunkID)
+			switch c.Op {
+			case store.ChangeRemove:
+				if err := idx.Remove(id); err != nil {
+					return stats, fmt.Errorf("remove chunk %d: %w", id, err)
+				}
+				stats.Removed++
+			case store.ChangeAdd:
+				// An add whose embedding has since been deleted has no
+				// vector; a later remove covers it.
+				if c.Vec != nil {
+					if err := idx.Replace(id, c.Vec); err != nil {
+						log.Printf("Skipping chunk %d: %v", id, err)
+					} else {
+						stats.Added++
+					}
+				}
+			}
+			stats.LastSeq = c.Seq
+		}
+
+		if len(changes) < batchSize {
+			return stats, nil
+		}
+	}
+}
+
+// Save writes idx to path if it is snapshot-capable, tagged with model and
+// the change seq its contents reflect. An empty path is a no-op.
+func Save(idx ann.Index, path, model string, seq int64) error {
+	if path == "" {
+		return nil
+	}
+	bf, ok := idx.(*ann.BruteForce)
+	if !ok {
+		return fmt.Errorf("index type %T does not support snapshots", idx)
+	}
+	return bf.Save(path, model, seq)
+}
@@ -0,0 +1,218 @@
+package snapshot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/sethfair/obsidx/internal/ann"
+	"github.com/sethfair/obsidx/internal/store"
+)
+
+const testDim = 4
+
+func openTestStore(t *testing.T) *store.SQLite {
+	t.Helper()
+	st, err := store.Open(filepath.Join(t.TempDir(), "test.db"), testDim)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+// putNote replaces the chunks of path with one chunk per vector and
+// returns the new chunk IDs.
+func putNote(t *testing.T, st *store.SQLite, path string, vecs ...[]float32) []uint64 {
+	t.Helper()
+	ctx := context.Background()
+	tx, err := st.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := st.MarkChunksInactive(ctx, tx, path); err != nil {
+		t.Fatalf("mark inactive: %v", err)
+	}
+	var ids []uint64
+	for i, vec := range vecs {
+		id, err := st.InsertChunk(ctx, tx, &store.Chunk{
+			Path:           path,
+			ChunkIndex:     i,
+			Content:        path,
+			ContentSHA256:  path,
+			CategoryWeight: 1,
+		})
+		if err != nil {
+			t.Fatalf("insert chunk: %v", err)
+		}
+		if err := st.InsertEmbedding(ctx, tx, &store.Embedding{ChunkID: id, Dim: testDim, Vec: vec}); err != nil {
+			t.Fatalf("insert embedding: %v", err)
+		}
+		ids = append(ids, uint64(id))
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+// indexIDs lists every ID in idx, sorted
+func indexIDs(t *testing.T, idx ann.Index) []uint64 {
+	t.Helper()
+	if idx.Size() == 0 {
+		return nil
+	}
+	ids, err := idx.Search([]float32{1, 1, 1, 1}, idx.Size())
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func equalIDs(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// loadFromDB is the ground truth: the store's active embeddings
+func loadFromDB(t *testing.T, st *store.SQLite) []uint64 {
+	t.Helper()
+	idx, _, err := Load(context.Background(), st, "", testDim, "m", ann.Options{})
+	if err != nil {
+		t.Fatalf("load from db: %v", err)
+	}
+	defer idx.Close()
+	return indexIDs(t, idx)
+}
+
+func TestLoadReplaysChangesAfterSnapshot(t *testing.T) {
+	ctx := context.Background()
+	st := openTestStore(t)
+	path := filepath.Join(t.TempDir(), FileName)
+
+	putNote(t, st, "/v/a.md", []float32{1, 0, 0, 0}, []float32{0, 1, 0, 0})
+	putNote(t, st, "/v/b.md", []float32{0, 0, 1, 0})
+
+	idx, seq, err := Load(ctx, st, path, testDim, "m", ann.Options{}) // no snapshot yet
+	if err != nil {
+		t.Fatalf("initial load: %v", err)
+	}
+	if err := Save(idx, path, "m", seq); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	idx.Close()
+
+	// Edit a, add c after the snapshot was taken
+	putNote(t, st, "/v/a.md", []float32{0, 0, 0, 1})
+	putNote(t, st, "/v/c.md", []float32{1, 1, 0, 0})
+
+	idx, gotSeq, err := Load(ctx, st, path, testDim, "m", ann.Options{})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	defer idx.Close()
+
+	latest, _ := st.GetLatestChangeSeq(ctx)
+	if gotSeq != latest {
+		t.Errorf("seq: got %d, want latest %d", gotSeq, latest)
+	}
+	if got, want := indexIDs(t, idx), loadFromDB(t, st); !equalIDs(got, want) {
+		t.Errorf("ids: got %v, want %v", got, want)
+	}
+}
+
+func TestLoadFallsBackToDatabase(t *testing.T) {
+	ctx := context.Background()
+	st := openTestStore(t)
+	putNote(t, st, "/v/a.md", []float32{1, 0, 0, 0}, []float32{0, 1, 0, 0})
+	want := loadFromDB(t, st)
+	seq, _ := st.GetLatestChangeSeq(ctx)
+
+	cases := []struct {
+		name  string
+		write func(path string) error
+	}{
+		{"missing", func(string) error { return nil }},
+		{"corrupt", func(path string) error { return os.WriteFile(path, []byte("not a snapshot"), 0644) }},
+		{"other model", func(path string) error { return ann.NewBruteForce(testDim).Save(path, "other", seq) }},
+		{"other dimension", func(path string) error { return ann.NewBruteForce(8).Save(path, "m", seq) }},
+		{"ahead of database", func(path string) error { return ann.NewBruteForce(testDim).Save(path, "m", seq+100) }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), FileName)
+			if err := tc.write(path); err != nil {
+				t.Fatal(err)
+			}
+			idx, gotSeq, err := Load(ctx, st, path, testDim, "m", ann.Options{})
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			defer idx.Close()
+			if gotSeq != seq {
+				t.Errorf("seq: got %d, want %d", gotSeq, seq)
+			}
+			if got := indexIDs(t, idx); !equalIDs(got, want) {
+				t.Errorf("ids: got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// A snapshot older than the garbage-collected part of the change log
+// cannot be replayed: a pruned remove would leave a deleted chunk behind.
+func TestLoadFallsBackWhenChangesPruned(t *testing.T) {
+	ctx := context.Background()
+	st := openTestStore(t)
+	path := filepath.Join(t.TempDir(), FileName)
+
+	putNote(t, st, "/v/a.md", []float32{1, 0, 0, 0})
+	idx, seq, err := Load(ctx, st, path, testDim, "m", ann.Options{})
+	if err != nil {
+		t.Fatalf("initial load: %v", err)
+	}
+	if err := Save(idx, path, "m", seq); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	idx.Close()
+
+	putNote(t, st, "/v/a.md", []float32{0, 1, 0, 0})
+	if _, err := st.CollectGarbage(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("gc: %v", err)
+	}
+
+	idx, _, err = Load(ctx, st, path, testDim, "m", ann.Options{})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	defer idx.Close()
+	if got, want := indexIDs(t, idx), loadFromDB(t, st); !equalIDs(got, want) {
+		t.Errorf("ids: got %v, want %v", got, want)
+	}
+}
+
+func TestPath(t *testing.T) {
+	db := filepath.Join("idx", "obsidx.db")
+	if got, want := Path("", db), filepath.Join("idx", FileName); got != want {
+		t.Errorf("default: got %q, want %q", got, want)
+	}
+	if got := Path("off", db); got != "" {
+		t.Errorf("off: got %q, want empty", got)
+	}
+	if got := Path("/tmp/x.snap", db); got != "/tmp/x.snap" {
+		t.Errorf("explicit: got %q", got)
+	}
+}
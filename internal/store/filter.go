@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ChunkFilter restricts retrieval to chunks whose note metadata matches.
+// Zero-value fields match everything; set fields are ANDed together, and
+// the values inside a list field are ORed. Tag and scope/status/type
+// comparisons are case-insensitive, tags may be given with or without
+// the leading '#', and a tag also matches its nested tags.
+type ChunkFilter struct {
+	TagsAny  []string `json:"tags_any,omitempty"`  // note has at least one of these tags
+	TagsAll  []string `json:"tags_all,omitempty"`  // note has every one of these tags
+	TagsNone []string `json:"tags_none,omitempty"` // note has none of these tags
+
+	Scope    []string `json:"scope,omitempty"`
+	Status   []string `json:"status,omitempty"`
+	NoteType []string `json:"note_type,omitempty"`
+
+	// PathPrefix matches the start of the stored path, or — if relative —
+	// the start of any path segment ("projects/alpha" matches
+	// "/vault/projects/alpha/x.md"). PathGlob is an SQLite GLOB pattern,
+	// matched anywhere below a path separator when relative.
+	PathPrefix string `json:"path_prefix,omitempty"`
+	PathGlob   string `json:"path_glob,omitempty"`
+
+	// File modification time bounds, RFC 3339 or YYYY-MM-DD (inclusive)
+	ModifiedAfter  string `json:"modified_after,omitempty"`
+	ModifiedBefore string `json:"modified_before,omitempty"`
+}
+
+// IsEmpty reports whether the filter matches every chunk
+func (f *ChunkFilter) IsEmpty() bool {
+	return f == nil || (len(f.TagsAny) == 0 && len(f.TagsAll) == 0 && len(f.TagsNone) == 0 &&
+		len(f.Scope) == 0 && len(f.Status) == 0 && len(f.NoteType) == 0 &&
+		f.PathPrefix == "" && f.PathGlob == "" &&
+		f.ModifiedAfter == "" && f.ModifiedBefore == "")
+}
+
+// where renders the filter as an SQL condition over chunks aliased c.
+// It always returns a valid condition ("1" for an empty filter).
+func (f *ChunkFilter) where() (string, []interface{}, error) {
+	if f.IsEmpty() {
+		return "1", nil, nil
+	}
+
+	var conds []string
+	var args []interface{}
+
+	// A tag also matches its nested tags: "project" matches "project/alpha".
+	// Rows written before tags were JSON-escaped may hold invalid JSON
+	// (e.g. an unescaped quote), which would fail json_each for the whole
+	// query; they match no tag until reindexed.
+	hasTag := func(tags []string) (string, []interface{}) {
+		ph := make([]string, len(tags))
+		a := make([]interface{}, 0, 2*len(tags))
+		for i, t := range tags {
+			tag := strings.ToLower(strings.Trim(strings.TrimPrefix(strings.TrimSpace(t), "#"), "/"))
+			ph[i] = `lower(t.value) = ? OR lower(t.value) LIKE ? ESCAPE '\'`
+			a = append(a, tag, escapeLike(tag)+"/%")
+		}
+		return "EXISTS (SELECT 1 FROM json_each(CASE WHEN json_valid(c.tags) THEN c.tags ELSE '[]' END) t WHERE " +
+			strings.Join(ph, " OR ") + ")", a
+	}
+
+	if len(f.TagsAny) > 0 {
+		cond, a := hasTag(f.TagsAny)
+		conds = append(conds, cond)
+		args = append(args, a...)
+	}
+	for _, tag := range f.TagsAll {
+		cond, a := hasTag([]string{tag})
+		conds = append(conds, cond)
+		args = append(args, a...)
+	}
+	if len(f.TagsNone) > 0 {
+		cond, a := hasTag(f.TagsNone)
+		conds = append(conds, "NOT "+cond)
+		args = append(args, a...)
+	}
+
+	in := func(column string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		ph := make([]string, len(values))
+		for i, v := range values {
+			ph[i] = "?"
+			args = append(args, strings.ToLower(strings.TrimSpace(v)))
+		}
+		conds = append(conds, fmt.Sprintf("lower(COALESCE(%s, '')) IN (%s)", column, strings.Join(ph, ",")))
+	}
+	in("c.scope", f.Scope)
+	in("c.status", f.Status)
+	in("c.note_type", f.NoteType)
+
+	if f.PathPrefix != "" {
+		escaped := escapeLike(f.PathPrefix)
+		if strings.HasPrefix(f.PathPrefix, "/") {
+			conds = append(conds, `c.path LIKE ? ESCAPE '\'`)
+			args = append(args, escaped+"%")
+		} else {
+			conds = append(conds, `(c.path LIKE ? ESCAPE '\' OR c.path LIKE ? ESCAPE '\')`)
+			args = append(args, escaped+"%", "%/"+escaped+"%")
+		}
+	}
+	if f.PathGlob != "" {
+		pattern := f.PathGlob
+		if !strings.HasPrefix(pattern, "/") && !strings.HasPrefix(pattern, "*") {
+			pattern = "*/" + pattern
+		}
+		conds = append(conds, "c.path GLOB ?")
+		args = append(args, pattern)
+	}
+
+	if f.ModifiedAfter != "" {
+		t, err := parseFilterTime(f.ModifiedAfter)
+		if err != nil {
+			return "", nil, fmt.Errorf("modified_after: %w", err)
+		}
+		conds = append(conds, "EXISTS (SELECT 1 FROM files fl WHERE fl.path = c.path AND fl.mtime_unix >= ?)")
+		args = append(args, t.Unix())
+	}
+	if f.ModifiedBefore != "" {
+		t, err := parseFilterTime(f.ModifiedBefore)
+		if err != nil {
+			return "", nil, fmt.Errorf("modified_before: %w", err)
+		}
+		// A bare date includes the whole day
+		if len(f.ModifiedBefore) == len("2006-01-02") {
+			t = t.Add(24*time.Hour - time.Second)
+		}
+		conds = append(conds, "EXISTS (SELECT 1 FROM files fl WHERE fl.path = c.path AND fl.mtime_unix <= ?)")
+		args = append(args, t.Unix())
+	}
+
+	return strings.Join(conds, " AND "), args, nil
+}
+
+// parseFilterTime accepts RFC 3339 timestamps or plain dates
+func parseFilterTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// escapeLike escapes LIKE wildcards so s matches literally (with ESCAPE '\')
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
+
+// FilterChunkIDs returns the IDs of all active chunks matching the filter,
+// for use as an allow-list in vector search. It returns nil for an empty
+// filter, meaning "no restriction".
+func (s *SQLite) FilterChunkIDs(ctx context.Context, f *ChunkFilter) (map[uint64]struct{}, error) {
+	if f.IsEmpty() {
+		return nil, nil
+	}
+	cond, args, err := f.where()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT c.id FROM chunks c WHERE c.active = 1 AND "+cond,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[uint64]struct{})
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = struct{}{}
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,163 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"testing"
+)
+
+// insertChunk stores c (plus a dummy embedding) as an active chunk
+func insertChunk(t *testing.T, st *SQLite, c *Chunk) uint64 {
+	t.Helper()
+	ctx := context.Background()
+	tx, err := st.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if c.ContentSHA256 == "" {
+		c.ContentSHA256 = c.Content
+	}
+	id, err := st.InsertChunk(ctx, tx, c)
+	if err != nil {
+		t.Fatalf("insert chunk: %v", err)
+	}
+	if err := st.InsertEmbedding(ctx, tx, &Embedding{ChunkID: id, Dim: 4, Vec: []float32{1, 0, 0, 0}}); err != nil {
+		t.Fatalf("insert embedding: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	return uint64(id)
+}
+
+// A chunk whose tags an old version wrote without escaping is not valid
+// JSON; it must not break tag filters for every other chunk
+func TestFilterChunkIDsSkipsLegacyTags(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	tagged := insertChunk(t, st, &Chunk{Path: "/vault/a.md", Content: "a", Tags: []string{"architecture"}})
+	legacy := insertChunk(t, st, &Chunk{Path: "/vault/b.md", Content: "b"})
+	if _, err := st.db.Exec("UPDATE chunks SET tags = ? WHERE id = ?", `["say "hi"","c:\\tmp","architecture"]`, legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter *ChunkFilter
+		want   []uint64
+	}{
+		{"tags any", &ChunkFilter{TagsAny: []string{"architecture"}}, []uint64{tagged}},
+		{"tags all", &ChunkFilter{TagsAll: []string{"architecture"}}, []uint64{tagged}},
+		{"tags none", &ChunkFilter{TagsNone: []string{"architecture"}}, []uint64{legacy}},
+	}
+	for _, tt := range tests {
+		got, err := st.FilterChunkIDs(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: FilterChunkIDs: %v", tt.name, err)
+		}
+		if _, ok := got[tt.want[0]]; !ok || len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilterChunkIDs(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	canon := insertChunk(t, st, &Chunk{Path: "/vault/projects/alpha/adr-1.md", Content: "a",
+		Tags: []string{"permanent-note", "architecture"}, Scope: "acme", Status: "active", NoteType: "decision"})
+	draft := insertChunk(t, st, &Chunk{Path: "/vault/projects/beta/spec.md", Content: "b",
+		Tags: []string{"Architecture"}, Scope: "acme", Status: "draft", NoteType: "spec"})
+	archived := insertChunk(t, st, &Chunk{Path: "/vault/archive/old_100%.md", Content: "c",
+		Tags: []string{"archive"}, Scope: "personal", Status: "deprecated"})
+	untagged := insertChunk(t, st, &Chunk{Path: "/vault/inbox.md", Content: "d"})
+	nested := insertChunk(t, st, &Chunk{Path: "/vault/notes/design.md", Content: "e",
+		Tags: []string{"Project/Alpha/design"}, ChunkTags: []string{"Project/Alpha/design"}})
+
+	for _, path := range []string{"/vault/projects/alpha/adr-1.md", "/vault/projects/beta/spec.md", "/vault/archive/old_100%.md", "/vault/inbox.md"} {
+		if err := st.UpsertFileInfo(ctx, &FileInfo{Path: path, SHA256: path, MtimeUnix: 1_700_000_000}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.UpsertFileInfo(ctx, &FileInfo{Path: "/vault/inbox.md", SHA256: "x", MtimeUnix: 1_800_000_000}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter *ChunkFilter
+		want   []uint64 // nil means "no restriction"
+	}{
+		{"nil filter", nil, nil},
+		{"empty filter", &ChunkFilter{}, nil},
+		{"tags any, case and hash insensitive", &ChunkFilter{TagsAny: []string{"#architecture"}}, []uint64{canon, draft}},
+		{"tags all", &ChunkFilter{TagsAll: []string{"architecture", "permanent-note"}}, []uint64{canon}},
+		{"tags none keeps untagged", &ChunkFilter{TagsNone: []string{"archive"}}, []uint64{canon, draft, untagged, nested}},
+		{"tag matches nested tags", &ChunkFilter{TagsAny: []string{"project"}}, []uint64{nested}},
+		{"nested tag prefix", &ChunkFilter{TagsAll: []string{"#project/alpha/"}}, []uint64{nested}},
+		{"partial tag name", &ChunkFilter{TagsAny: []string{"proj", "project/alp"}}, []uint64{}},
+		{"status", &ChunkFilter{Status: []string{"Active", "draft"}}, []uint64{canon, draft}},
+		{"scope and type", &ChunkFilter{Scope: []string{"acme"}, NoteType: []string{"spec"}}, []uint64{draft}},
+		{"relative path prefix", &ChunkFilter{PathPrefix: "projects/"}, []uint64{canon, draft}},
+		{"absolute path prefix", &ChunkFilter{PathPrefix: "/vault/projects/alpha"}, []uint64{canon}},
+		{"path prefix escapes wildcards", &ChunkFilter{PathPrefix: "archive/old_100%"}, []uint64{archived}},
+		{"relative glob", &ChunkFilter{PathGlob: "adr-*.md"}, []uint64{canon}},
+		{"modified after", &ChunkFilter{ModifiedAfter: "2025-01-01"}, []uint64{untagged}},
+		{"modified before includes the day", &ChunkFilter{ModifiedBefore: "2023-11-14"}, []uint64{canon, draft, archived}},
+		{"no match", &ChunkFilter{TagsAny: []string{"nonexistent"}}, []uint64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := st.FilterChunkIDs(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("FilterChunkIDs: %v", err)
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("expected nil (unrestricted), got %v", got)
+				}
+				return
+			}
+			var ids []uint64
+			for id := range got {
+				ids = append(ids, id)
+			}
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+			if len(ids) != len(tt.want) {
+				t.Fatalf("got %v, want %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", ids, tt.want)
+					break
+				}
+			}
+		})
+	}
+
+	if _, err := st.FilterChunkIDs(ctx, &ChunkFilter{ModifiedAfter: "last tuesday"}); err == nil {
+		t.Error("expected error for unparseable date")
+	}
+}
+
+func TestKeywordSearchAppliesFilter(t *testing.T) {
+	st := openTestStore(t)
+	if !st.HasKeywordSearch() {
+		t.Skip("FTS5 not compiled in (run with -tags sqlite_fts5)")
+	}
+	ctx := context.Background()
+
+	keep := insertChunk(t, st, &Chunk{Path: "/vault/a.md", Content: "pangolin rollout plan", Tags: []string{"project"}})
+	insertChunk(t, st, &Chunk{Path: "/vault/b.md", Content: "pangolin archive notes", Tags: []string{"archive"}})
+
+	hits, err := st.KeywordSearch(ctx, "pangolin", 10, &ChunkFilter{TagsNone: []string{"archive"}})
+	if err != nil {
+		t.Fatalf("KeywordSearch: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ID != keep {
+		t.Errorf("expected only chunk %d, got %+v", keep, hits)
+	}
+}
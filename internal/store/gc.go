@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrChangesPruned is returned by GetChangesSince when changes after the
+// requested seq have been garbage collected. The caller cannot catch up by
+// replaying and must reload from the active embeddings instead.
+var ErrChangesPruned = errors.New("chunk change log pruned past requested seq")
+
+// changesPrunedKey is the index_meta key holding the highest pruned seq
+const changesPrunedKey = "changes_pruned_through_seq"
+
+// GCStats reports what CollectGarbage deleted
+type GCStats struct {
+	Chunks     int64 // inactive chunks past the retention window
+	Embeddings int64 // their embeddings, plus any left without a chunk
+	Changes    int64 // change log entries past the retention window
+	PrunedSeq  int64 // highest change seq now pruned (0 if none ever)
+}
+
+// CollectGarbage deletes chunks deactivated before cutoff along with their
+// embeddings, embeddings whose chunk no longer exists, and change log
+// entries recorded before cutoff, in one transaction. Chunks deactivated by
+// a version that did not record the time count from their creation.
+//
+// Inactive chunks inside the window are kept so their embeddings can still
+// be reused (GetEmbeddingsByInputHash). The freed pages stay in the
+// database file until Vacuum.
+func (s *SQLite) CollectGarbage(ctx context.Context, cutoff time.Time) (GCStats, error) {
+	var stats GCStats
+	before := cutoff.Unix()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return stats, err
+	}
+	defer tx.Rollback()
+
+	exec := func(query string, args ...interface{}) (int64, error) {
+		res, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	}
+
+	const expired = `active = 0 AND COALESCE(deactivated_at_unix, created_at_unix) < ?`
+	if stats.Embeddings, err = exec(
+		"DELETE FROM embeddings WHERE chunk_id IN (SELECT id FROM chunks WHERE "+expired+")", before,
+	); err != nil {
+		return stats, fmt.Errorf("delete embeddings: %w", err)
+	}
+	if stats.Chunks, err = exec("DELETE FROM chunks WHERE "+expired, before); err != nil {
+		return stats, fmt.Errorf("delete chunks: %w", err)
+	}
+	orphans, err := exec("DELETE FROM embeddings WHERE chunk_id NOT IN (SELECT id FROM chunks)")
+	if err != nil {
+		return stats, fmt.Errorf("delete orphaned embeddings: %w", err)
+	}
+	stats.Embeddings += orphans
+
+	// Prune the change log by seq, not timestamp, so what remains is always
+	// a contiguous tail readers can replay.
+	var pruneThrough sql.NullInt64
+	if err := tx.QueryRowContext(ctx,
+		"SELECT MAX(seq) FROM chunk_changes WHERE changed_at_unix < ?", before,
+	).Scan(&pruneThrough); err != nil {
+		return stats, fmt.Errorf("find prunable changes: %w", err)
+	}
+	prunedSeq, err := getPrunedSeq(ctx, tx)
+	if err != nil {
+		return stats, err
+	}
+	if pruneThrough.Valid && pruneThrough.Int64 > prunedSeq {
+		prunedSeq = pruneThrough.Int64
+		if stats.Changes, err = exec("DELETE FROM chunk_changes WHERE seq <= ?", prunedSeq); err != nil {
+			return stats, fmt.Errorf("delete changes: %w", err)
+		}
+		if _, err := exec(
+			`INSERT INTO index_meta (key, value) VALUES (?, ?)
+			 ON CONFLICT(key) DO UPDATE SET value = excluded.value`,
+			changesPrunedKey, strconv.FormatInt(prunedSeq, 10),
+		); err != nil {
+			return stats, fmt.Errorf("record pruned seq: %w", err)
+		}
+	}
+	stats.PrunedSeq = prunedSeq
+
+	return stats, tx.Commit()
+}
+
+// getPrunedSeq returns the highest change seq deleted by CollectGarbage
+func getPrunedSeq(ctx context.Context, q interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}) (int64, error) {
+	var value string
+	err := q.QueryRowContext(ctx, "SELECT value FROM index_meta WHERE key = ?", changesPrunedKey).Scan(&value)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// SpaceUsage describes how the database file's pages are used
+type SpaceUsage struct {
+	PageSize  int64
+	Pages     int64 // pages in the database file
+	FreePages int64 // unused pages, reclaimed only by Vacuum
+}
+
+// FileBytes is the size of the database file
+func (u SpaceUsage) FileBytes() int64 { return u.PageSize * u.Pages }
+
+// FreeBytes is the space unused pages take up
+func (u SpaceUsage) FreeBytes() int64 { return u.PageSize * u.FreePages }
+
+// GetSpaceUsage reports the database file's page usage
+func (s *SQLite) GetSpaceUsage(ctx context.Context) (SpaceUsage, error) {
+	var u SpaceUsage
+	for _, p := range []struct {
+		pragma string
+		dst    *int64
+	}{
+		{"page_size", &u.PageSize},
+		{"page_count", &u.Pages},
+		{"freelist_count", &u.FreePages},
+	} {
+		if err := s.db.QueryRowContext(ctx, "PRAGMA "+p.pragma).Scan(p.dst); err != nil {
+			return u, fmt.Errorf("%s: %w", p.pragma, err)
+		}
+	}
+	return u, nil
+}
+
+// Vacuum rewrites the database file without its free pages and truncates
+// the write-ahead log. It needs as much free disk as the database takes
+// and blocks writers while it runs.
+func (s *SQLite) Vacuum(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("vacuum: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("checkpoint wal: %w", err)
+	}
+	return nil
+}
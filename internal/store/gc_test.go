@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func countRows(t *testing.T, st *SQLite, query string) int {
+	t.Helper()
+	var n int
+	if err := st.db.QueryRow(query).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+func TestCollectGarbageRespectsRetention(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	insertNote(t, st, "/vault/a.md", "a v1 one", "a v1 two")
+	insertNote(t, st, "/vault/a.md", "a v2") // deactivates v1
+	insertNote(t, st, "/vault/b.md", "b v1")
+
+	// Nothing has been inactive for an hour yet
+	stats, err := st.CollectGarbage(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("gc: %v", err)
+	}
+	if stats != (GCStats{}) {
+		t.Errorf("expected nothing collected inside the retention window, got %+v", stats)
+	}
+
+	// An orphaned embedding (its chunk deleted out from under it)
+	if _, err := st.db.Exec("INSERT INTO embeddings (chunk_id, dim, vec) VALUES (9999, 4, x'00')"); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, _ := st.GetLatestChangeSeq(ctx)
+	stats, err = st.CollectGarbage(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("gc: %v", err)
+	}
+	want := GCStats{Chunks: 2, Embeddings: 3, Changes: 6, PrunedSeq: latest}
+	if stats != want {
+		t.Errorf("got %+v, want %+v", stats, want)
+	}
+	if n := countRows(t, st, "SELECT COUNT(*) FROM chunks"); n != 2 {
+		t.Errorf("expected the 2 active chunks to remain, got %d", n)
+	}
+	if n := countRows(t, st, "SELECT COUNT(*) FROM embeddings"); n != 2 {
+		t.Errorf("expected 2 embeddings to remain, got %d", n)
+	}
+
+	// The log was pruned: the latest seq survives, and readers behind it
+	// are told to reload rather than silently missing changes.
+	if got, _ := st.GetLatestChangeSeq(ctx); got != latest {
+		t.Errorf("latest seq after gc: got %d, want %d", got, latest)
+	}
+	if _, err := st.GetChangesSince(ctx, latest-1, 10); !errors.Is(err, ErrChangesPruned) {
+		t.Errorf("expected ErrChangesPruned, got %v", err)
+	}
+	insertNote(t, st, "/vault/c.md", "c v1")
+	changes, err := st.GetChangesSince(ctx, latest, 10)
+	if err != nil || len(changes) != 1 || changes[0].Seq <= latest {
+		t.Errorf("expected the new add after seq %d, got %+v, %v", latest, changes, err)
+	}
+}
+
+func TestSpaceUsageAfterVacuum(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	for i := 0; i < 50; i++ {
+		insertNote(t, st, "/vault/a.md", strings.Repeat("x", 4000))
+	}
+	if _, err := st.CollectGarbage(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("gc: %v", err)
+	}
+
+	before, err := st.GetSpaceUsage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.FreePages == 0 {
+		t.Fatal("expected free pages after deleting chunks")
+	}
+	if err := st.Vacuum(ctx); err != nil {
+		t.Fatalf("vacuum: %v", err)
+	}
+	after, err := st.GetSpaceUsage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.FreePages != 0 || after.FileBytes() >= before.FileBytes() {
+		t.Errorf("vacuum did not shrink the file: before %+v, after %+v", before, after)
+	}
+}
@@ -0,0 +1,280 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LinksPendingKey is the index_meta key set ("1") when tracked notes have
+// not had their links extracted yet, e.g. after upgrading a database
+// indexed before the links table existed.
+const LinksPendingKey = "links_pending"
+
+// Link is a link from a note to a note, heading or file
+type Link struct {
+	SourcePath    string
+	SourceChunkID int64  // active chunk holding the link; 0 in front matter or a chunk that was not stored
+	Line          int    // 0-based line in the source note
+	LinkType      string // wikilink | embed | markdown
+	Target        string // as written, without heading or alias; "" links within the note
+	Heading       string
+	Alias         string
+	ResolvedPath  string // indexed note the link points to; "" for attachments and missing notes
+
+	// The linking chunk's section and line range, filled in by reads when
+	// SourceChunkID is set
+	SourceHeadingPath string
+	SourceStartLine   int
+	SourceEndLine     int
+}
+
+// rowQuerier is a *sql.DB or *sql.Tx
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// ReplaceLinksTx replaces the links from sourcePath. Each link is assigned
+// the active chunk spanning its line, so call it after inserting the note's
+// chunks, and resolved against the tracked files. Links to notes indexed
+// later are resolved when those notes are (see UpsertFileInfoTx).
+func (s *SQLite) ReplaceLinksTx(ctx context.Context, tx *sql.Tx, sourcePath string, links []Link) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM links WHERE source_path = ?", sourcePath); err != nil {
+		return fmt.Errorf("delete links: %w", err)
+	}
+
+	for i := range links {
+		l := &links[i]
+		l.SourcePath = sourcePath
+
+		var chunkID sql.NullInt64
+		err := tx.QueryRowContext(ctx,
+			`SELECT id FROM chunks WHERE path = ? AND active = 1 AND ? BETWEEN start_line AND end_line
+			 ORDER BY id DESC LIMIT 1`,
+			sourcePath, l.Line,
+		).Scan(&chunkID)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("find chunk for link: %w", err)
+		}
+		l.SourceChunkID = chunkID.Int64
+
+		if l.ResolvedPath, err = resolveLink(ctx, tx, sourcePath, l.LinkType, l.Target); err != nil {
+			return fmt.Errorf("resolve link %q: %w", l.Target, err)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO links (source_path, source_chunk_id, line, link_type, target, heading, alias,
+			                    target_name, resolved_path)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			sourcePath, chunkID, l.Line, l.LinkType, l.Target, nullString(l.Heading), nullString(l.Alias),
+			linkTargetName(l.Target), nullString(l.ResolvedPath),
+		); err != nil {
+			return fmt.Errorf("insert link: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetLinksFrom returns the links from a note in the order they appear
+func (s *SQLite) GetLinksFrom(ctx context.Context, sourcePath string) ([]Link, error) {
+	return s.queryLinks(ctx, "l.source_path = ? ORDER BY l.line, l.id", sourcePath)
+}
+
+// GetBacklinks returns the links from other notes to a note, by source
+// path and line
+func (s *SQLite) GetBacklinks(ctx context.Context, notePath string) ([]Link, error) {
+	return s.queryLinks(ctx,
+		"l.resolved_path = ? AND l.source_path != ? ORDER BY l.source_path, l.line, l.id",
+		notePath, notePath)
+}
+
+// ResolveNote finds the tracked note that name refers to: a tracked path,
+// or else the note [[name]] would link to. It returns "" if none matches.
+func (s *SQLite) ResolveNote(ctx context.Context, name string) (string, error) {
+	var p string
+	err := s.db.QueryRowContext(ctx, "SELECT path FROM files WHERE path = ?", name).Scan(&p)
+	if err != sql.ErrNoRows {
+		return p, err
+	}
+	return resolveLink(ctx, s.db, "", "wikilink", name)
+}
+
+// queryLinks reads the links matching cond (over links aliased l, with
+// any ORDER BY), with their chunk's section and line range
+func (s *SQLite) queryLinks(ctx context.Context, cond string, args ...interface{}) ([]Link, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT l.source_path, COALESCE(l.source_chunk_id, 0), l.line, l.link_type, l.target,
+		        COALESCE(l.heading, ''), COALESCE(l.alias, ''), COALESCE(l.resolved_path, ''),
+		        COALESCE(c.heading_path, ''), COALESCE(c.start_line, 0), COALESCE(c.end_line, 0)
+		 FROM links l LEFT JOIN chunks c ON c.id = l.source_chunk_id
+		 WHERE `+cond,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []Link
+	for rows.Next() {
+		var l Link
+		if err := rows.Scan(&l.SourcePath, &l.SourceChunkID, &l.Line, &l.LinkType, &l.Target,
+			&l.Heading, &l.Alias, &l.ResolvedPath,
+			&l.SourceHeadingPath, &l.SourceStartLine, &l.SourceEndLine); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// resolveLink finds the tracked note a link points to, the way Obsidian
+// does: markdown links relative to the source note first, then any note
+// whose path ends in the target (shortest path wins), matching case
+// insensitively and with ".md" implied. It returns "" if none matches.
+func resolveLink(ctx context.Context, q rowQuerier, source, linkType, target string) (string, error) {
+	if target == "" {
+		return source, nil // [[#Heading]]
+	}
+
+	candidates := []string{target}
+	if !strings.EqualFold(path.Ext(target), ".md") {
+		candidates = []string{target + ".md", target}
+	}
+
+	queryPath := func(query string, args ...interface{}) (string, error) {
+		var p string
+		err := q.QueryRowContext(ctx, query, args...).Scan(&p)
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return p, err
+	}
+
+	if linkType == "markdown" && !strings.HasPrefix(target, "/") {
+		for _, c := range candidates {
+			p, err := queryPath("SELECT path FROM files WHERE lower(path) = lower(?)",
+				filepath.Join(filepath.Dir(source), filepath.FromSlash(c)))
+			if p != "" || err != nil {
+				return p, err
+			}
+		}
+	}
+
+	for _, c := range candidates {
+		suffix := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(c)), "/")
+		if suffix == "" {
+			continue
+		}
+		// A vault indexed by relative path has top-level notes without a '/'
+		p, err := queryPath(`SELECT path FROM files WHERE path LIKE ? ESCAPE '\' OR lower(path) = lower(?)
+		                     ORDER BY length(path), path LIMIT 1`,
+			"%/"+escapeLike(suffix), suffix)
+		if p != "" || err != nil {
+			return p, err
+		}
+	}
+	return "", nil
+}
+
+// reresolveLinksTx resolves again every link matching cond (an SQL
+// condition on links), after the files it may resolve to have changed
+func reresolveLinksTx(ctx context.Context, tx *sql.Tx, cond string, args ...interface{}) error {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, source_path, link_type, target FROM links WHERE "+cond, args...)
+	if err != nil {
+		return fmt.Errorf("find links: %w", err)
+	}
+	type pending struct {
+		id                   int64
+		source, kind, target string
+	}
+	var links []pending
+	for rows.Next() {
+		var l pending
+		if err := rows.Scan(&l.id, &l.source, &l.kind, &l.target); err != nil {
+			rows.Close()
+			return err
+		}
+		links = append(links, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, l := range links {
+		resolved, err := resolveLink(ctx, tx, l.source, l.kind, l.target)
+		if err != nil {
+			return fmt.Errorf("resolve link %q: %w", l.target, err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE links SET resolved_path = ? WHERE id = ?", nullString(resolved), l.id,
+		); err != nil {
+			return fmt.Errorf("update link: %w", err)
+		}
+	}
+	return nil
+}
+
+// linkTargetName is the lower-cased file name a link target or note path
+// is known by, without ".md"
+func linkTargetName(target string) string {
+	if target == "" {
+		return ""
+	}
+	name := path.Base(filepath.ToSlash(target))
+	return strings.TrimSuffix(strings.ToLower(name), ".md")
+}
+
+// GetNoteNeighbors maps each of paths to the other notes it links to or is
+// linked from, each once. Unresolved links and links within a note are
+// ignored.
+func (s *SQLite) GetNoteNeighbors(ctx context.Context, paths []string) (map[string][]string, error) {
+	neighbors := make(map[string][]string, len(paths))
+	if len(paths) == 0 {
+		return neighbors, nil
+	}
+
+	wanted := make(map[string]bool, len(paths))
+	ph := make([]string, len(paths))
+	args := make([]interface{}, 0, 2*len(paths))
+	for i, p := range paths {
+		wanted[p] = true
+		ph[i] = "?"
+		args = append(args, p)
+	}
+	in := strings.Join(ph, ",")
+	args = append(args, args...)
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT DISTINCT source_path, resolved_path FROM links
+		 WHERE resolved_path IS NOT NULL AND source_path != resolved_path
+		   AND (source_path IN (`+in+`) OR resolved_path IN (`+in+`))`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[[2]string]bool)
+	add := func(from, to string) {
+		if wanted[from] && !seen[[2]string{from, to}] {
+			seen[[2]string{from, to}] = true
+			neighbors[from] = append(neighbors[from], to)
+		}
+	}
+	for rows.Next() {
+		var source, target string
+		if err := rows.Scan(&source, &target); err != nil {
+			return nil, err
+		}
+		add(source, target)
+		add(target, source)
+	}
+	return neighbors, rows.Err()
+}
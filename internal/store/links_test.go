@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestReplaceLinksResolvesTargets(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	for _, p := range []string{
+		"/vault/Hub.md",
+		"/vault/projects/alpha/Plan.md",
+		"/vault/archive/projects/alpha/Plan.md",
+		"/vault/adr/ADR-002 SQLite.md",
+		"/vault/notes/source.md",
+		"Inbox.md", // top level of a vault indexed as "."
+	} {
+		if err := st.UpsertFileInfo(ctx, &FileInfo{Path: p, SHA256: p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chunkID := insertChunk(t, st, &Chunk{Path: "/vault/notes/source.md", Content: "body", StartLine: 2, EndLine: 9})
+
+	tests := []struct {
+		link Link
+		want string
+	}{
+		{Link{LinkType: "wikilink", Target: "hub"}, "/vault/Hub.md"},
+		{Link{LinkType: "wikilink", Target: "Plan"}, "/vault/projects/alpha/Plan.md"}, // shortest path
+		{Link{LinkType: "wikilink", Target: "archive/projects/alpha/Plan"}, "/vault/archive/projects/alpha/Plan.md"},
+		{Link{LinkType: "wikilink", Target: "ADR-002 SQLite.md"}, "/vault/adr/ADR-002 SQLite.md"},
+		{Link{LinkType: "markdown", Target: "../archive/projects/alpha/Plan.md"}, "/vault/archive/projects/alpha/Plan.md"},
+		{Link{LinkType: "markdown", Target: "projects/alpha/Plan.md"}, "/vault/projects/alpha/Plan.md"}, // vault-absolute
+		{Link{LinkType: "wikilink", Heading: "Intro"}, "/vault/notes/source.md"},
+		{Link{LinkType: "wikilink", Target: "inbox"}, "Inbox.md"},
+		{Link{LinkType: "embed", Target: "chart.png"}, ""},
+		{Link{LinkType: "wikilink", Target: "lan"}, ""}, // not a suffix match inside a name
+	}
+
+	links := make([]Link, len(tests))
+	for i, tt := range tests {
+		links[i] = tt.link
+		links[i].Line = i
+	}
+	tx, err := st.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.ReplaceLinksTx(ctx, tx, "/vault/notes/source.md", links); err != nil {
+		t.Fatalf("ReplaceLinksTx: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := st.GetLinksFrom(ctx, "/vault/notes/source.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(tests) {
+		t.Fatalf("got %d links, want %d", len(got), len(tests))
+	}
+	for i, tt := range tests {
+		if got[i].ResolvedPath != tt.want {
+			t.Errorf("%q: resolved to %q, want %q", tt.link.Target, got[i].ResolvedPath, tt.want)
+		}
+		wantChunk := int64(0)
+		if i >= 2 {
+			wantChunk = int64(chunkID)
+		}
+		if got[i].SourceChunkID != wantChunk {
+			t.Errorf("line %d: chunk %d, want %d", i, got[i].SourceChunkID, wantChunk)
+		}
+	}
+}
+
+func TestGetBacklinks(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	for _, p := range []string{"/vault/adr/ADR-002.md", "/vault/plan.md", "/vault/log.md"} {
+		if err := st.UpsertFileInfo(ctx, &FileInfo{Path: p, SHA256: p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	insertChunk(t, st, &Chunk{Path: "/vault/plan.md", HeadingPath: "Plan > Storage", Content: "x", StartLine: 3, EndLine: 8})
+
+	replace := func(source string, links ...Link) {
+		t.Helper()
+		tx, err := st.BeginTx(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+		if err := st.ReplaceLinksTx(ctx, tx, source, links); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	replace("/vault/plan.md",
+		Link{LinkType: "wikilink", Target: "ADR-002", Alias: "the ADR", Line: 5},
+		Link{LinkType: "wikilink", Target: "missing", Line: 6})
+	replace("/vault/log.md", Link{LinkType: "markdown", Target: "adr/ADR-002.md", Line: 0})
+	replace("/vault/adr/ADR-002.md", Link{LinkType: "wikilink", Heading: "Context", Line: 2})
+
+	back, err := st.GetBacklinks(ctx, "/vault/adr/ADR-002.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(back) != 2 || back[0].SourcePath != "/vault/log.md" || back[1].SourcePath != "/vault/plan.md" {
+		t.Fatalf("backlinks: %+v", back)
+	}
+	if b := back[1]; b.Alias != "the ADR" || b.SourceHeadingPath != "Plan > Storage" ||
+		b.SourceStartLine != 3 || b.SourceEndLine != 8 {
+		t.Errorf("backlink chunk details: %+v", b)
+	}
+	if back[0].SourceChunkID != 0 || back[0].SourceHeadingPath != "" {
+		t.Errorf("link outside a chunk: %+v", back[0])
+	}
+
+	for name, want := range map[string]string{
+		"/vault/plan.md":        "/vault/plan.md",
+		"adr-002":               "/vault/adr/ADR-002.md",
+		"adr/ADR-002.md":        "/vault/adr/ADR-002.md",
+		"missing":               "",
+		"/elsewhere/ADR-003.md": "",
+	} {
+		if got, err := st.ResolveNote(ctx, name); err != nil || got != want {
+			t.Errorf("ResolveNote(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	neighbors, err := st.GetNoteNeighbors(ctx, []string{"/vault/adr/ADR-002.md", "/vault/plan.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"/vault/adr/ADR-002.md": {"/vault/log.md", "/vault/plan.md"},
+		"/vault/plan.md":        {"/vault/adr/ADR-002.md"},
+	}
+	for p := range neighbors {
+		sort.Strings(neighbors[p])
+	}
+	if !reflect.DeepEqual(neighbors, want) {
+		t.Errorf("neighbors = %v, want %v", neighbors, want)
+	}
+}
@@ -0,0 +1,208 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"os"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migration upgrades the schema from version-1 to version. The schema
+// version is kept in PRAGMA user_version.
+//
+// Databases created before versioning (user_version 0) may already have
+// any prefix of these changes, applied by CREATE ... IF NOT EXISTS or by
+// migrate.sh, so every migration must tolerate finding its work done.
+// Append new migrations; never edit or reorder released ones.
+type migration struct {
+	version int
+	name    string
+	up      func(ctx context.Context, tx *sql.Tx) error
+}
+
+var migrations = []migration{
+	{1, "baseline schema", execFile("0001_baseline.sql")},
+	{2, "chunk change log", execFile("0002_chunk_changes.sql")},
+	{3, "chunks.category for canon lookups", func(ctx context.Context, tx *sql.Tx) error {
+		// Added by migrate.sh on some databases (with a 'project' default);
+		// GetCanonChunkIDs queries it.
+		if err := addColumn(ctx, tx, "chunks", "category", "TEXT"); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_chunks_category ON chunks(category)")
+		return err
+	}},
+	{4, "embeddings.model and a chunks.content_sha256 index for embedding reuse", func(ctx context.Context, tx *sql.Tx) error {
+		// NULL (model unknown) is never reused
+		if err := addColumn(ctx, tx, "embeddings", "model", "TEXT"); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_chunks_content_sha256 ON chunks(content_sha256)")
+		return err
+	}},
+	{5, "chunks.deactivated_at_unix for garbage collection", func(ctx context.Context, tx *sql.Tx) error {
+		return addColumn(ctx, tx, "chunks", "deactivated_at_unix", "INTEGER")
+	}},
+	{6, "embeddings.input_sha256: reuse keyed on the embedded text, not chunks.content_sha256", func(ctx context.Context, tx *sql.Tx) error {
+		if err := addColumn(ctx, tx, "embeddings", "input_sha256", "TEXT"); err != nil {
+			return err
+		}
+		// Until now the embedded text was exactly the chunk content. Reuse
+		// no longer looks chunks up by content hash, so migration 4's index
+		// would only slow down every chunk write.
+		stmts := []string{
+			`UPDATE embeddings SET input_sha256 = (SELECT content_sha256 FROM chunks WHERE id = chunk_id)
+			 WHERE input_sha256 IS NULL`,
+			"CREATE INDEX IF NOT EXISTS idx_embeddings_input_sha256 ON embeddings(input_sha256)",
+			"DROP INDEX IF EXISTS idx_chunks_content_sha256",
+		}
+		for _, stmt := range stmts {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{7, "chunks.chunk_tags: inline tags per chunk", func(ctx context.Context, tx *sql.Tx) error {
+		return addColumn(ctx, tx, "chunks", "chunk_tags", "TEXT")
+	}},
+	{8, "links table for the note graph", execFile("0008_links.sql")},
+}
+
+// SchemaVersion is the schema version this build creates and expects
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// MigrationStep names one migration
+type MigrationStep struct {
+	Version int
+	Name    string
+}
+
+// Migrate brings the database at path up to SchemaVersion and returns the
+// version it started from and the migrations applied. Open does this
+// automatically; Migrate exists to upgrade explicitly and to preview.
+//
+// With dryRun set, the pending migrations run in a transaction that is
+// rolled back, so they are checked against the real database but nothing
+// changes. A dry run on a missing file lists every migration.
+func Migrate(ctx context.Context, path string, dryRun bool) (int, []MigrationStep, error) {
+	if dryRun {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return 0, pendingMigrations(0), nil
+		}
+	}
+	db, err := sql.Open("sqlite3", dsn(path))
+	if err != nil {
+		return 0, nil, fmt.Errorf("open db: %w", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	return migrate(ctx, db, dryRun)
+}
+
+func pendingMigrations(from int) []MigrationStep {
+	var steps []MigrationStep
+	for _, m := range migrations {
+		if m.version > from {
+			steps = append(steps, MigrationStep{Version: m.version, Name: m.name})
+		}
+	}
+	return steps
+}
+
+// migrate applies every migration newer than the database's version in
+// one transaction, so a failed upgrade leaves the database as it was.
+func migrate(ctx context.Context, db *sql.DB, dryRun bool) (int, []MigrationStep, error) {
+	return migrateTo(ctx, db, SchemaVersion(), dryRun)
+}
+
+func migrateTo(ctx context.Context, db *sql.DB, target int, dryRun bool) (int, []MigrationStep, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	var from int
+	if err := tx.QueryRowContext(ctx, "PRAGMA user_version").Scan(&from); err != nil {
+		return 0, nil, fmt.Errorf("read schema version: %w", err)
+	}
+	if from > SchemaVersion() {
+		return from, nil, fmt.Errorf("database schema version %d is newer than this build supports (%d)", from, SchemaVersion())
+	}
+
+	var applied []MigrationStep
+	for _, m := range migrations {
+		if m.version <= from || m.version > target {
+			continue
+		}
+		if err := m.up(ctx, tx); err != nil {
+			return from, applied, fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+		applied = append(applied, MigrationStep{Version: m.version, Name: m.name})
+	}
+	if len(applied) == 0 || dryRun {
+		return from, applied, nil
+	}
+
+	// PRAGMA arguments cannot be bound; the version is our own integer
+	last := applied[len(applied)-1].Version
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", last)); err != nil {
+		return from, nil, fmt.Errorf("set schema version: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return from, nil, err
+	}
+	return from, applied, nil
+}
+
+// execFile returns a migration that runs one of the embedded SQL files
+func execFile(name string) func(context.Context, *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		script, err := migrationFiles.ReadFile("migrations/" + name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, string(script))
+		return err
+	}
+}
+
+// addColumn adds a column unless the table already has it (CREATE TABLE IF
+// NOT EXISTS leaves existing tables alone, and SQLite has no ADD COLUMN IF
+// NOT EXISTS).
+func addColumn(ctx context.Context, tx *sql.Tx, table, column, decl string) error {
+	exists, err := hasColumn(ctx, tx, table, column)
+	if err != nil || exists {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl))
+	if err != nil {
+		return fmt.Errorf("add %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+func hasColumn(ctx context.Context, tx *sql.Tx, table, column string) (bool, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
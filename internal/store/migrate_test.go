@@ -0,0 +1,236 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// loadFixture builds a database at a temp path from a testdata SQL script
+func loadFixture(t *testing.T, name string) string {
+	t.Helper()
+	script, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "fixture.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(string(script)); err != nil {
+		t.Fatalf("load %s: %v", name, err)
+	}
+	return path
+}
+
+// schemaColumns maps each table in db to its set of columns
+func schemaColumns(t *testing.T, db *sql.DB) map[string]map[string]bool {
+	t.Helper()
+	rows, err := db.Query(`SELECT m.name, p.name FROM sqlite_master m, pragma_table_info(m.name) p
+	                       WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%' AND m.name NOT LIKE 'chunks_fts%'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	cols := make(map[string]map[string]bool)
+	for rows.Next() {
+		var table, col string
+		if err := rows.Scan(&table, &col); err != nil {
+			t.Fatal(err)
+		}
+		if cols[table] == nil {
+			cols[table] = make(map[string]bool)
+		}
+		cols[table][col] = true
+	}
+	return cols
+}
+
+func userVersion(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var v int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// checkUpgraded verifies a migrated store has the fresh schema, still
+// holds the fixture's note and accepts writes from the current code.
+func checkUpgraded(t *testing.T, st *SQLite, want map[string]map[string]bool, withData bool) {
+	t.Helper()
+	ctx := context.Background()
+
+	if v := userVersion(t, st.db); v != SchemaVersion() {
+		t.Errorf("schema version %d, want %d", v, SchemaVersion())
+	}
+	got := schemaColumns(t, st.db)
+	for table, cols := range want {
+		for col := range cols {
+			if !got[table][col] {
+				t.Errorf("missing %s.%s", table, col)
+			}
+		}
+	}
+
+	var unused int
+	if err := st.db.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'idx_chunks_content_sha256'",
+	).Scan(&unused); err != nil || unused != 0 {
+		t.Errorf("unused content hash index kept (%d, %v)", unused, err)
+	}
+
+	if withData {
+		chunks, err := st.GetChunksByIDs(ctx, []uint64{2})
+		if err != nil {
+			t.Fatalf("read fixture chunk: %v", err)
+		}
+		if len(chunks) != 1 || chunks[0].Content != "new body" || chunks[0].Vec[1] != 1 {
+			t.Errorf("fixture chunk not preserved: %+v", chunks)
+		}
+	}
+
+	insertNote(t, st, "/vault/b.md", "written after the upgrade")
+	if _, err := st.GetCanonChunkIDs(ctx, 10); err != nil {
+		t.Errorf("GetCanonChunkIDs: %v", err)
+	}
+	if _, err := st.CollectGarbage(ctx, time.Now()); err != nil {
+		t.Errorf("CollectGarbage: %v", err)
+	}
+}
+
+func freshSchema(t *testing.T) map[string]map[string]bool {
+	t.Helper()
+	return schemaColumns(t, openTestStore(t).db)
+}
+
+func TestOpenMigratesUnversionedFixtures(t *testing.T) {
+	want := freshSchema(t)
+	for _, fixture := range []string{
+		"v0_baseline.sql",
+		"v0_migrate_sh.sql",
+		"v0_change_log.sql",
+		"v0_unversioned_latest.sql",
+	} {
+		t.Run(fixture, func(t *testing.T) {
+			path := loadFixture(t, fixture)
+			st, err := Open(path, 4)
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			defer st.Close()
+			checkUpgraded(t, st, want, true)
+		})
+	}
+}
+
+// Embeddings written before input hashes were recorded stay reusable:
+// their input was the chunk content.
+func TestMigrationBackfillsInputHash(t *testing.T) {
+	st, err := Open(loadFixture(t, "v0_unversioned_latest.sql"), 4)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer st.Close()
+
+	got, err := st.GetEmbeddingsByInputHash(context.Background(), "ollama-nomic-embed-text", []string{"h-old", "h-new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Errorf("expected both fixture embeddings reusable, got %v", got)
+	}
+}
+
+func TestOpenMigratesEachPriorVersion(t *testing.T) {
+	want := freshSchema(t)
+	for v := 1; v < SchemaVersion(); v++ {
+		t.Run(fmt.Sprintf("v%d", v), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "old.db")
+			db, err := sql.Open("sqlite3", dsn(path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := migrateTo(context.Background(), db, v, false); err != nil {
+				t.Fatalf("build v%d: %v", v, err)
+			}
+			if got := userVersion(t, db); got != v {
+				t.Fatalf("built version %d, want %d", got, v)
+			}
+			db.Close()
+
+			st, err := Open(path, 4)
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			defer st.Close()
+			checkUpgraded(t, st, want, false)
+		})
+	}
+}
+
+func TestMigrateDryRunChangesNothing(t *testing.T) {
+	ctx := context.Background()
+	path := loadFixture(t, "v0_baseline.sql")
+
+	from, steps, err := Migrate(ctx, path, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if from != 0 || len(steps) != SchemaVersion() {
+		t.Errorf("dry run: from %d with %d steps, want 0 and %d", from, len(steps), SchemaVersion())
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if v := userVersion(t, db); v != 0 {
+		t.Errorf("dry run set schema version %d", v)
+	}
+	if schemaColumns(t, db)["chunk_changes"] != nil {
+		t.Error("dry run created chunk_changes")
+	}
+
+	if _, steps, err := Migrate(ctx, path, false); err != nil || len(steps) != SchemaVersion() {
+		t.Fatalf("migrate: %d steps, %v", len(steps), err)
+	}
+	if from, steps, err := Migrate(ctx, path, true); err != nil || from != SchemaVersion() || len(steps) != 0 {
+		t.Errorf("up to date: from %d, %d steps, %v", from, len(steps), err)
+	}
+}
+
+func TestMigrateDryRunMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "none.db")
+	_, steps, err := Migrate(context.Background(), path, true)
+	if err != nil || len(steps) != SchemaVersion() {
+		t.Errorf("got %d steps, %v", len(steps), err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("dry run created the database file")
+	}
+}
+
+func TestOpenRejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "future.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", SchemaVersion()+1)); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if st, err := Open(path, 4); err == nil {
+		st.Close()
+		t.Fatal("expected an error opening a newer schema")
+	}
+}
@@ -1,5 +1,6 @@
--- SQLite schema for obsidx
--- This is the source of truth for all chunk data
+-- Baseline obsidx schema: the tables as they were before the schema was
+-- versioned. Unversioned databases already have them, so every statement
+-- must stay IF NOT EXISTS.
 
 -- Files table: tracks processed files to avoid reprocessing unchanged content
 CREATE TABLE IF NOT EXISTS files (
@@ -0,0 +1,10 @@
+-- Chunk change log: one row per chunk that became searchable ('add') or
+-- was deactivated ('remove'), written in the same transaction as the change
+-- itself. seq is monotonic, so long-running readers (the recall server) can
+-- poll for everything after the last seq they applied.
+CREATE TABLE IF NOT EXISTS chunk_changes (
+  seq INTEGER PRIMARY KEY AUTOINCREMENT,
+  chunk_id INTEGER NOT NULL,
+  op TEXT NOT NULL,
+  changed_at_unix INTEGER NOT NULL
+);
@@ -0,0 +1,27 @@
+-- Links between notes, replaced whenever their source note is re-indexed.
+-- target is the link as written (without heading or alias); resolved_path
+-- is the indexed note it points to, or NULL for attachments and notes not
+-- in the index. target_name (lower-cased file name without .md) finds the
+-- dangling links a newly indexed note resolves.
+CREATE TABLE IF NOT EXISTS links (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  source_path TEXT NOT NULL,
+  source_chunk_id INTEGER,  -- stored chunk holding the link; NULL in front matter or skipped chunks
+  line INTEGER NOT NULL,    -- 0-based
+  link_type TEXT NOT NULL,  -- wikilink | embed | markdown
+  target TEXT NOT NULL,
+  heading TEXT,
+  alias TEXT,
+  target_name TEXT NOT NULL,
+  resolved_path TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_links_source_path ON links(source_path);
+CREATE INDEX IF NOT EXISTS idx_links_resolved_path ON links(resolved_path);
+CREATE INDEX IF NOT EXISTS idx_links_target_name ON links(target_name);
+
+-- Notes indexed before links were extracted have none until the indexer
+-- backfills them (see linksPendingKey)
+INSERT INTO index_meta (key, value)
+SELECT 'links_pending', '1' WHERE EXISTS (SELECT 1 FROM files)
+ON CONFLICT(key) DO NOTHING;
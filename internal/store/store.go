@@ -3,8 +3,9 @@ package store
 import (
 	"context"
 	"database/sql"
-	_ "embed"
 	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
@@ -14,13 +15,15 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-//go:embed schema.sql
-var schemaSQL string
+// ErrKeywordSearchUnavailable is returned by KeywordSearch when the binary
+// was built without FTS5 support.
+var ErrKeywordSearchUnavailable = errors.New("keyword search unavailable: build with -tags sqlite_fts5")
 
 // SQLite is the authoritative store for chunks and embeddings
 type SQLite struct {
 	db  *sql.DB
 	dim int
+	fts bool // chunks_fts is available and maintained
 }
 
 // FileInfo tracks processed files
@@ -48,7 +51,8 @@ type Chunk struct {
 	Scope          string
 	NoteType       string
 	CategoryWeight float32
-	Tags           []string
+	Tags           []string // note tags: front matter and inline, for filtering
+	ChunkTags      []string // inline tags written in this chunk
 }
 
 // Embedding represents a vector embedding
@@ -56,6 +60,26 @@ type Embedding struct {
 	ChunkID int64
 	Dim     int
 	Vec     []float32
+	Model   string // embedder that produced Vec; "" if unknown
+
+	// InputSHA256 hashes the exact text embedded, which may include context
+	// beyond the chunk content; "" if unknown
+	InputSHA256 string
+}
+
+// Chunk change operations recorded in chunk_changes
+const (
+	ChangeAdd    = "add"
+	ChangeRemove = "remove"
+)
+
+// ChunkChange is one entry of the chunk change log. Vec is set for adds
+// whose embedding still exists.
+type ChunkChange struct {
+	Seq     int64
+	ChunkID int64
+	Op      string
+	Vec     []float32
 }
 
 // ChunkWithEmbedding combines chunk metadata with its vector
@@ -64,12 +88,16 @@ type ChunkWithEmbedding struct {
 	Vec []float32
 }
 
-// Open creates or opens a SQLite database
-func Open(path string, dimension int) (*SQLite, error) {
+// dsn is the connection string for the database at path
+func dsn(path string) string {
 	// Enhanced connection string for better concurrency handling
-	connStr := path + "?_journal_mode=WAL&_synchronous=NORMAL&_cache_size=-64000&_busy_timeout=5000&_txlock=immediate"
+	return path + "?_journal_mode=WAL&_synchronous=NORMAL&_cache_size=-64000&_busy_timeout=5000&_txlock=immediate"
+}
 
-	db, err := sql.Open("sqlite3", connStr)
+// Open creates or opens a SQLite database, migrating its schema to
+// SchemaVersion first
+func Open(path string, dimension int) (*SQLite, error) {
+	db, err := sql.Open("sqlite3", dsn(path))
 	if err != nil {
 		return nil, fmt.Errorf("open db: %w", err)
 	}
@@ -84,13 +112,84 @@ func Open(path string, dimension int) (*SQLite, error) {
 		return nil, fmt.Errorf("ping db: %w", err)
 	}
 
-	// Initialize schema
-	if _, err := db.Exec(schemaSQL); err != nil {
+	// Create or upgrade the schema
+	if _, _, err := migrate(context.Background(), db, false); err != nil {
 		db.Close()
-		return nil, fmt.Errorf("init schema: %w", err)
+		return nil, fmt.Errorf("migrate schema: %w", err)
 	}
 
-	return &SQLite{db: db, dim: dimension}, nil
+	s := &SQLite{db: db, dim: dimension}
+	if err := s.initFTS(context.Background()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init fts: %w", err)
+	}
+
+	return s, nil
+}
+
+// initFTS sets up chunks_fts, the FTS5 keyword index over active chunks.
+// FTS5 is only compiled into go-sqlite3 with the sqlite_fts5 build tag
+// (build.sh sets it). Without it keyword search is unavailable, and an
+// existing chunks_fts table is flagged stale since this process cannot
+// keep it in sync; the next FTS5-enabled open rebuilds it from chunks.
+func (s *SQLite) initFTS(ctx context.Context) error {
+	var enabled int
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT sqlite_compileoption_used('ENABLE_FTS5')",
+	).Scan(&enabled); err != nil {
+		return fmt.Errorf("check fts5: %w", err)
+	}
+
+	var exists int
+	if err := s.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'chunks_fts'",
+	).Scan(&exists); err != nil {
+		return err
+	}
+
+	if enabled == 0 {
+		if exists > 0 {
+			return s.SetIndexMeta(ctx, map[string]string{"fts_stale": "1"})
+		}
+		return nil
+	}
+	s.fts = true
+
+	stale, err := s.GetIndexMeta(ctx, "fts_stale")
+	if err != nil {
+		return err
+	}
+	if exists > 0 && stale != "1" {
+		return nil
+	}
+
+	// Create (or rebuild) the index from the active chunks
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmts := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS chunks_fts USING fts5(
+		   content, heading_path, tokenize = 'unicode61 remove_diacritics 2'
+		 )`,
+		"DELETE FROM chunks_fts",
+		`INSERT INTO chunks_fts (rowid, content, heading_path)
+		 SELECT id, content, COALESCE(heading_path, '') FROM chunks WHERE active = 1`,
+		"DELETE FROM index_meta WHERE key = 'fts_stale'",
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// HasKeywordSearch reports whether the FTS5 keyword index is available
+func (s *SQLite) HasKeywordSearch() bool {
+	return s.fts
 }
 
 // Close closes the database
@@ -122,19 +221,19 @@ func (s *SQLite) GetFileInfo(ctx context.Context, path string) (*FileInfo, error
 
 // UpsertFileInfo updates or inserts file tracking info
 func (s *SQLite) UpsertFileInfo(ctx context.Context, fi *FileInfo) error {
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO files (path, sha256, mtime_unix, indexed_at_unix)
-		 VALUES (?, ?, ?, ?)
-		 ON CONFLICT(path) DO UPDATE SET
-		   sha256 = excluded.sha256,
-		   mtime_unix = excluded.mtime_unix,
-		   indexed_at_unix = excluded.indexed_at_unix`,
-		fi.Path, fi.SHA256, fi.MtimeUnix, fi.IndexedAtUnix,
-	)
-	return err
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := s.UpsertFileInfoTx(ctx, tx, fi); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// UpsertFileInfoTx updates or inserts file tracking info within a transaction
+// UpsertFileInfoTx updates or inserts file tracking info within a
+// transaction, and resolves dangling links that name the file
 func (s *SQLite) UpsertFileInfoTx(ctx context.Context, tx *sql.Tx, fi *FileInfo) error {
 	_, err := tx.ExecContext(ctx,
 		`INSERT INTO files (path, sha256, mtime_unix, indexed_at_unix)
@@ -145,16 +244,143 @@ func (s *SQLite) UpsertFileInfoTx(ctx context.Context, tx *sql.Tx, fi *FileInfo)
 		   indexed_at_unix = excluded.indexed_at_unix`,
 		fi.Path, fi.SHA256, fi.MtimeUnix, fi.IndexedAtUnix,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	return reresolveLinksTx(ctx, tx, "resolved_path IS NULL AND target_name = ?", linkTargetName(fi.Path))
 }
 
-// MarkChunksInactive marks all chunks for a file as inactive (soft delete)
-func (s *SQLite) MarkChunksInactive(ctx context.Context, tx *sql.Tx, path string) error {
-	_, err := tx.ExecContext(ctx,
-		"UPDATE chunks SET active = 0 WHERE path = ?",
+// DeleteFileInfoTx removes file tracking info within a transaction, along
+// with the file's links. Links to it are resolved again, usually to nothing.
+func (s *SQLite) DeleteFileInfoTx(ctx context.Context, tx *sql.Tx, path string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM files WHERE path = ?", path); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM links WHERE source_path = ?", path); err != nil {
+		return fmt.Errorf("delete links: %w", err)
+	}
+	return reresolveLinksTx(ctx, tx, "resolved_path = ?", path)
+}
+
+// GetFilesBySHA256 returns every tracked file with the given content hash
+func (s *SQLite) GetFilesBySHA256(ctx context.Context, sha string) ([]FileInfo, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT path, sha256, mtime_unix, indexed_at_unix FROM files WHERE sha256 = ? ORDER BY path",
+		sha,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []FileInfo
+	for rows.Next() {
+		var fi FileInfo
+		if err := rows.Scan(&fi.Path, &fi.SHA256, &fi.MtimeUnix, &fi.IndexedAtUnix); err != nil {
+			return nil, err
+		}
+		files = append(files, fi)
+	}
+	return files, rows.Err()
+}
+
+// ListFilePaths returns the paths of all tracked files
+func (s *SQLite) ListFilePaths(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT path FROM files ORDER BY path")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
+
+// RenameFileTx moves a file's chunks and links to a new path and drops the
+// old file tracking row. Chunk IDs and embeddings are untouched, so a rename
+// never needs re-embedding; the caller upserts FileInfo for the new path,
+// which resolves links to the note again.
+func (s *SQLite) RenameFileTx(ctx context.Context, tx *sql.Tx, oldPath, newPath string) error {
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE chunks SET path = ? WHERE path = ?",
+		newPath, oldPath,
+	); err != nil {
+		return fmt.Errorf("re-key chunks: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE links SET source_path = ? WHERE source_path = ?",
+		newPath, oldPath,
+	); err != nil {
+		return fmt.Errorf("re-key links: %w", err)
+	}
+	if err := s.DeleteFileInfoTx(ctx, tx, oldPath); err != nil {
+		return err
+	}
+	// Relative links from the note now start from its new folder
+	return reresolveLinksTx(ctx, tx, "source_path = ?", newPath)
+}
+
+// MarkChunksInactive marks all chunks for a file as inactive (soft delete),
+// records a remove change for each chunk that was active, and returns the
+// IDs of those chunks so callers can drop them from the search index.
+func (s *SQLite) MarkChunksInactive(ctx context.Context, tx *sql.Tx, path string) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id FROM chunks WHERE path = ? AND active = 1 ORDER BY id",
 		path,
 	)
-	return err
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now().Unix()
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO chunk_changes (chunk_id, op, changed_at_unix)
+		 SELECT id, ?, ? FROM chunks WHERE path = ? AND active = 1 ORDER BY id`,
+		ChangeRemove, now, path,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("record removes: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE chunks SET active = 0, deactivated_at_unix = ? WHERE path = ? AND active = 1",
+		now, path,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.fts {
+		for _, id := range ids {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM chunks_fts WHERE rowid = ?", id); err != nil {
+				return nil, fmt.Errorf("remove from fts: %w", err)
+			}
+		}
+	}
+	return ids, nil
 }
 
 // InsertChunk inserts a new chunk and returns its ID
@@ -162,39 +388,130 @@ func (s *SQLite) InsertChunk(ctx context.Context, tx *sql.Tx, c *Chunk) (int64,
 	c.CreatedAtUnix = time.Now().Unix()
 	c.Active = true
 
-	// Convert tags to JSON string
-	tagsJSON := "[]"
-	if len(c.Tags) > 0 {
-		tagParts := make([]string, len(c.Tags))
-		for i, tag := range c.Tags {
-			tagParts[i] = fmt.Sprintf(`"%s"`, tag)
-		}
-		tagsJSON = "[" + strings.Join(tagParts, ",") + "]"
+	// Convert tags to JSON arrays (queried with json_each by ChunkFilter)
+	tagsJSON, err := encodeTags(c.Tags)
+	if err != nil {
+		return 0, fmt.Errorf("encode tags: %w", err)
+	}
+	chunkTagsJSON, err := encodeTags(c.ChunkTags)
+	if err != nil {
+		return 0, fmt.Errorf("encode chunk tags: %w", err)
 	}
 
 	result, err := tx.ExecContext(ctx,
 		`INSERT INTO chunks (path, heading_path, chunk_index, content, content_sha256, 
 		                     start_line, end_line, active, created_at_unix,
-		                     status, scope, note_type, category_weight, tags)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		                     status, scope, note_type, category_weight, tags, chunk_tags)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		c.Path, c.HeadingPath, c.ChunkIndex, c.Content, c.ContentSHA256,
 		c.StartLine, c.EndLine, 1, c.CreatedAtUnix,
-		c.Status, c.Scope, c.NoteType, c.CategoryWeight, tagsJSON,
+		c.Status, c.Scope, c.NoteType, c.CategoryWeight, tagsJSON, chunkTagsJSON,
 	)
 	if err != nil {
 		return 0, err
 	}
-	return result.LastInsertId()
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if s.fts {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO chunks_fts (rowid, content, heading_path) VALUES (?, ?, ?)",
+			id, c.Content, c.HeadingPath,
+		); err != nil {
+			return 0, fmt.Errorf("insert into fts: %w", err)
+		}
+	}
+	return id, nil
 }
 
-// InsertEmbedding inserts a vector for a chunk
+// InsertEmbedding inserts a vector for a chunk and records an add change:
+// the chunk becomes searchable once its vector exists.
 func (s *SQLite) InsertEmbedding(ctx context.Context, tx *sql.Tx, e *Embedding) error {
 	vecBlob := Float32ToBytes(e.Vec)
 	_, err := tx.ExecContext(ctx,
-		"INSERT INTO embeddings (chunk_id, dim, vec) VALUES (?, ?, ?)",
-		e.ChunkID, e.Dim, vecBlob,
+		"INSERT INTO embeddings (chunk_id, dim, vec, model, input_sha256) VALUES (?, ?, ?, ?, ?)",
+		e.ChunkID, e.Dim, vecBlob, nullString(e.Model), nullString(e.InputSHA256),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO chunk_changes (chunk_id, op, changed_at_unix) VALUES (?, ?, ?)",
+		e.ChunkID, ChangeAdd, time.Now().Unix(),
 	)
-	return err
+	if err != nil {
+		return fmt.Errorf("record add: %w", err)
+	}
+	return nil
+}
+
+// nullString stores "" as NULL
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// GetEmbeddingsByInputHash returns a stored vector for each of the given
+// embedding input hashes (Embedding.InputSHA256) that model has already
+// embedded at the store's dimension, keyed by hash. Inactive chunks count:
+// their embeddings are kept, so text that moves within a note or comes
+// back after an edit is not embedded again. Hashes without a match are
+// absent from the map.
+func (s *SQLite) GetEmbeddingsByInputHash(ctx context.Context, model string, hashes []string) (map[string][]float32, error) {
+	const batchSize = 500 // stay well under SQLite's bound parameter limit
+
+	found := make(map[string][]float32)
+	if model == "" {
+		return found, nil
+	}
+	for start := 0; start < len(hashes); start += batchSize {
+		batch := hashes[start:min(start+batchSize, len(hashes))]
+
+		args := []interface{}{model, s.dim}
+		placeholders := make([]string, len(batch))
+		for i, h := range batch {
+			placeholders[i] = "?"
+			args = append(args, h)
+		}
+		// Newest first, so the first row seen for a hash wins
+		rows, err := s.db.QueryContext(ctx,
+			`SELECT input_sha256, vec
+			 FROM embeddings
+			 WHERE model = ? AND dim = ? AND input_sha256 IN (`+strings.Join(placeholders, ",")+`)
+			 ORDER BY chunk_id DESC`,
+			args...,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var hash string
+			var vecBlob []byte
+			if err := rows.Scan(&hash, &vecBlob); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if _, ok := found[hash]; ok {
+				continue
+			}
+			vec, err := BytesToFloat32(vecBlob)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("decode vec for input %s: %w", hash, err)
+			}
+			found[hash] = vec
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
 }
 
 // BeginTx starts a transaction
@@ -220,17 +537,159 @@ func (s *SQLite) StreamActiveEmbeddings(ctx context.Context) (*sql.Rows, error)
 	)
 }
 
+// GetLatestChangeSeq returns the highest recorded change sequence (0 if
+// none), including changes CollectGarbage has since pruned
+func (s *SQLite) GetLatestChangeSeq(ctx context.Context) (int64, error) {
+	var seq int64
+	err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(seq), 0) FROM chunk_changes").Scan(&seq)
+	if err != nil {
+		return 0, err
+	}
+	pruned, err := getPrunedSeq(ctx, s.db)
+	if err != nil {
+		return 0, err
+	}
+	return max(seq, pruned), nil
+}
+
+// GetChangesSince returns up to limit changes with seq > afterSeq in seq
+// order. Adds carry the chunk's vector; an add whose embedding has since
+// been deleted is returned without one and should be skipped. It returns
+// ErrChangesPruned if changes after afterSeq were garbage collected.
+func (s *SQLite) GetChangesSince(ctx context.Context, afterSeq int64, limit int) ([]ChunkChange, error) {
+	pruned, err := getPrunedSeq(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	if afterSeq < pruned {
+		return nil, fmt.Errorf("%w: after %d, pruned through %d", ErrChangesPruned, afterSeq, pruned)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT ch.seq, ch.chunk_id, ch.op, e.vec
+		 FROM chunk_changes ch
+		 LEFT JOIN embeddings e ON ch.op = ? AND e.chunk_id = ch.chunk_id
+		 WHERE ch.seq > ?
+		 ORDER BY ch.seq
+		 LIMIT ?`,
+		ChangeAdd, afterSeq, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []ChunkChange
+	for rows.Next() {
+		var c ChunkChange
+		var vecBlob []byte
+		if err := rows.Scan(&c.Seq, &c.ChunkID, &c.Op, &vecBlob); err != nil {
+			return nil, err
+		}
+		if vecBlob != nil {
+			vec, err := BytesToFloat32(vecBlob)
+			if err != nil {
+				return nil, fmt.Errorf("decode vec for chunk %d: %w", c.ChunkID, err)
+			}
+			c.Vec = vec
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// KeywordHit is a BM25 match from the keyword index
+type KeywordHit struct {
+	ID    uint64
+	Score float64 // BM25 relevance, higher is better
+}
+
+// KeywordSearch returns up to k active chunks matching any term of query
+// and the filter (nil for none), ranked by BM25 (heading matches weigh
+// double). Terms are matched as quoted phrases, so FTS5 query syntax in
+// user input is inert.
+func (s *SQLite) KeywordSearch(ctx context.Context, query string, k int, filter *ChunkFilter) ([]KeywordHit, error) {
+	if !s.fts {
+		return nil, ErrKeywordSearchUnavailable
+	}
+	match := ftsQuery(query)
+	if match == "" || k <= 0 {
+		return nil, nil
+	}
+	cond, filterArgs, err := filter.where()
+	if err != nil {
+		return nil, err
+	}
+
+	args := append([]interface{}{match}, filterArgs...)
+	args = append(args, k)
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT chunks_fts.rowid, bm25(chunks_fts, 1.0, 2.0) AS rank
+		 FROM chunks_fts
+		 JOIN chunks c ON c.id = chunks_fts.rowid
+		 WHERE chunks_fts MATCH ? AND `+cond+`
+		 ORDER BY rank, chunks_fts.rowid
+		 LIMIT ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("fts query: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []KeywordHit
+	for rows.Next() {
+		var h KeywordHit
+		var rank float64
+		if err := rows.Scan(&h.ID, &rank); err != nil {
+			return nil, err
+		}
+		h.Score = -rank // FTS5 bm25() is negative, lower is better
+		hits = append(hits, h)
+	}
+	return hits, rows.Err()
+}
+
+// ftsQuery turns free text into an FTS5 query that ORs every
+// whitespace-separated term as a quoted phrase
+func ftsQuery(query string) string {
+	var terms []string
+	for _, f := range strings.Fields(query) {
+		f = strings.ReplaceAll(f, `"`, `""`)
+		terms = append(terms, `"`+f+`"`)
+	}
+	return strings.Join(terms, " OR ")
+}
+
 // GetChunksByIDs fetches chunks with embeddings by their IDs
 func (s *SQLite) GetChunksByIDs(ctx context.Context, ids []uint64) ([]ChunkWithEmbedding, error) {
+	return s.getChunksByIDs(ctx, ids, true)
+}
+
+// GetChunkMetaByIDs fetches chunks by their IDs like GetChunksByIDs but
+// leaves Vec nil, skipping the embedding blobs. Use it when the caller
+// already has similarity scores from the search index.
+func (s *SQLite) GetChunkMetaByIDs(ctx context.Context, ids []uint64) ([]ChunkWithEmbedding, error) {
+	return s.getChunksByIDs(ctx, ids, false)
+}
+
+func (s *SQLite) getChunksByIDs(ctx context.Context, ids []uint64, withVec bool) ([]ChunkWithEmbedding, error) {
 	if len(ids) == 0 {
 		return nil, nil
 	}
 
+	// Only chunks with an embedding are searchable, so the join stays even
+	// when the vector itself is not selected.
+	vecCol := "NULL"
+	if withVec {
+		vecCol = "e.vec"
+	}
+
 	// Build query with placeholders
 	query := `SELECT c.id, c.path, c.heading_path, c.chunk_index, c.content, 
 	                 c.content_sha256, c.start_line, c.end_line, c.active, 
 	                 c.created_at_unix, c.status, c.scope, c.note_type,
-	                 c.category_weight, c.tags, e.dim, e.vec
+	                 c.category_weight, c.tags, COALESCE(c.chunk_tags, ''), e.dim, ` + vecCol + `
 	          FROM chunks c
 	          JOIN embeddings e ON c.id = e.chunk_id
 	          WHERE c.active = 1 AND c.id IN (`
@@ -255,7 +714,7 @@ func (s *SQLite) GetChunksByIDs(ctx context.Context, ids []uint64) ([]ChunkWithE
 	for rows.Next() {
 		var cwe ChunkWithEmbedding
 		var vecBlob []byte
-		var tagsJSON string
+		var tagsJSON, chunkTagsJSON string
 		var dim int
 		var active int
 
@@ -263,7 +722,7 @@ func (s *SQLite) GetChunksByIDs(ctx context.Context, ids []uint64) ([]ChunkWithE
 			&cwe.ID, &cwe.Path, &cwe.HeadingPath, &cwe.ChunkIndex, &cwe.Content,
 			&cwe.ContentSHA256, &cwe.StartLine, &cwe.EndLine, &active,
 			&cwe.CreatedAtUnix, &cwe.Status, &cwe.Scope, &cwe.NoteType,
-			&cwe.CategoryWeight, &tagsJSON, &dim, &vecBlob,
+			&cwe.CategoryWeight, &tagsJSON, &chunkTagsJSON, &dim, &vecBlob,
 		)
 		if err != nil {
 			return nil, err
@@ -271,31 +730,55 @@ func (s *SQLite) GetChunksByIDs(ctx context.Context, ids []uint64) ([]ChunkWithE
 
 		cwe.Active = active == 1
 
-		// Parse tags from JSON
-		if tagsJSON != "" && tagsJSON != "[]" {
-			// Simple JSON array parsing
-			tagsJSON = strings.Trim(tagsJSON, "[]")
-			if tagsJSON != "" {
-				for _, tag := range strings.Split(tagsJSON, ",") {
-					tag = strings.Trim(strings.TrimSpace(tag), `"`)
-					if tag != "" {
-						cwe.Tags = append(cwe.Tags, tag)
-					}
-				}
-			}
-		}
+		cwe.Tags = parseTags(tagsJSON)
+		cwe.ChunkTags = parseTags(chunkTagsJSON)
 
-		vec, err := BytesToFloat32(vecBlob)
-		if err != nil {
-			return nil, fmt.Errorf("decode vec for chunk %d: %w", cwe.ID, err)
+		if withVec {
+			vec, err := BytesToFloat32(vecBlob)
+			if err != nil {
+				return nil, fmt.Errorf("decode vec for chunk %d: %w", cwe.ID, err)
+			}
+			cwe.Vec = vec
 		}
-		cwe.Vec = vec
 		results = append(results, cwe)
 	}
 
 	return results, rows.Err()
 }
 
+// encodeTags renders tags as the JSON array stored in the tags columns
+func encodeTags(tags []string) (string, error) {
+	if len(tags) == 0 {
+		return "[]", nil
+	}
+	b, err := json.Marshal(tags)
+	return string(b), err
+}
+
+// parseTags decodes the tags column. Rows written before tags were
+// JSON-encoded with escaping fall back to a plain split.
+func parseTags(tagsJSON string) []string {
+	if tagsJSON == "" || tagsJSON == "[]" {
+		return nil
+	}
+	var tags []string
+	if err := json.Unmarshal([]byte(tagsJSON), &tags); err == nil {
+		return tags
+	}
+	for _, tag := range strings.Split(strings.Trim(tagsJSON, "[]"), ",") {
+		tag = strings.Trim(strings.TrimSpace(tag), `"`)
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// EmbedPrefixesKey is the index_meta key holding the task prefixes (JSON
+// embed.Prefixes) the active chunks were embedded with, so queries can be
+// embedded to match. Indexes built before prefixes have no such key.
+const EmbedPrefixesKey = "embed_prefixes"
+
 // GetIndexMeta retrieves index metadata value
 func (s *SQLite) GetIndexMeta(ctx context.Context, key string) (string, error) {
 	var value string
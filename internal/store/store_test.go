@@ -0,0 +1,249 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func openTestStore(t *testing.T) *SQLite {
+	t.Helper()
+	st, err := Open(filepath.Join(t.TempDir(), "test.db"), 4)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+// insertNote stores one active chunk per content string under path and
+// returns their IDs.
+func insertNote(t *testing.T, st *SQLite, path string, contents ...string) []int64 {
+	t.Helper()
+	ctx := context.Background()
+	tx, err := st.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := st.MarkChunksInactive(ctx, tx, path); err != nil {
+		t.Fatalf("mark inactive: %v", err)
+	}
+	var ids []int64
+	for i, content := range contents {
+		id, err := st.InsertChunk(ctx, tx, &Chunk{
+			Path:           path,
+			HeadingPath:    "Section",
+			ChunkIndex:     i,
+			Content:        content,
+			ContentSHA256:  content,
+			CategoryWeight: 1,
+		})
+		if err != nil {
+			t.Fatalf("insert chunk: %v", err)
+		}
+		if err := st.InsertEmbedding(ctx, tx, &Embedding{ChunkID: id, Dim: 4, Vec: []float32{1, 0, 0, 0}}); err != nil {
+			t.Fatalf("insert embedding: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestKeywordSearchFindsExactIdentifiers(t *testing.T) {
+	st := openTestStore(t)
+	if !st.HasKeywordSearch() {
+		t.Skip("FTS5 not compiled in (run with -tags sqlite_fts5)")
+	}
+	ctx := context.Background()
+
+	ids := insertNote(t, st, "/vault/a.md",
+		"Rollout of ticket OPS-4711 is blocked on the database migration.",
+		"General thoughts about deployment pipelines and release cadence.",
+	)
+	other := insertNote(t, st, "/vault/b.md", "Meeting notes with Zanzibar about pricing.")
+
+	hits, err := st.KeywordSearch(ctx, "OPS-4711", 10, nil)
+	if err != nil {
+		t.Fatalf("KeywordSearch: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ID != uint64(ids[0]) {
+		t.Fatalf("expected only chunk %d for ticket id, got %+v", ids[0], hits)
+	}
+
+	hits, err = st.KeywordSearch(ctx, "zanzibar", 10, nil)
+	if err != nil {
+		t.Fatalf("KeywordSearch: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ID != uint64(other[0]) {
+		t.Errorf("expected case-insensitive match on chunk %d, got %+v", other[0], hits)
+	}
+
+	// FTS5 syntax in user input must not break the query.
+	if _, err := st.KeywordSearch(ctx, `"unbalanced AND ( NEAR -`, 10, nil); err != nil {
+		t.Errorf("KeywordSearch with FTS5 syntax characters: %v", err)
+	}
+}
+
+func TestKeywordSearchDropsDeactivatedChunks(t *testing.T) {
+	st := openTestStore(t)
+	if !st.HasKeywordSearch() {
+		t.Skip("FTS5 not compiled in (run with -tags sqlite_fts5)")
+	}
+	ctx := context.Background()
+
+	insertNote(t, st, "/vault/a.md", "The old wording mentions quokka explicitly.")
+	insertNote(t, st, "/vault/a.md", "The new wording talks about something else.")
+
+	hits, err := st.KeywordSearch(ctx, "quokka", 10, nil)
+	if err != nil {
+		t.Fatalf("KeywordSearch: %v", err)
+	}
+	if len(hits) != 0 {
+		t.Errorf("deactivated chunk still matched: %+v", hits)
+	}
+}
+
+// A database written by a build without FTS5 must have its keyword index
+// rebuilt from the active chunks on the next FTS5-enabled open.
+func TestKeywordIndexRebuiltWhenStale(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	st, err := Open(dbPath, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.HasKeywordSearch() {
+		st.Close()
+		t.Skip("FTS5 not compiled in (run with -tags sqlite_fts5)")
+	}
+	ctx := context.Background()
+
+	// Simulate writes the index never saw.
+	st.fts = false
+	ids := insertNote(t, st, "/vault/a.md", "Chunk written while keyword index was unmaintained: axolotl.")
+	if err := st.SetIndexMeta(ctx, map[string]string{"fts_stale": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	st.Close()
+
+	st, err = Open(dbPath, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	hits, err := st.KeywordSearch(ctx, "axolotl", 10, nil)
+	if err != nil {
+		t.Fatalf("KeywordSearch: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ID != uint64(ids[0]) {
+		t.Errorf("stale keyword index not rebuilt; got %+v", hits)
+	}
+}
+
+func TestKeywordSearchUnavailableWithoutFTS(t *testing.T) {
+	st := openTestStore(t)
+	st.fts = false
+	if _, err := st.KeywordSearch(context.Background(), "anything", 10, nil); err != ErrKeywordSearchUnavailable {
+		t.Errorf("expected ErrKeywordSearchUnavailable, got %v", err)
+	}
+}
+
+func TestGetChunkMetaByIDsSkipsVectors(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	id := insertChunk(t, st, &Chunk{Path: "/vault/a.md", Content: "alpha #db", Tags: []string{"go", "db"},
+		ChunkTags: []string{"db"}, CategoryWeight: 1.2})
+
+	full, err := st.GetChunksByIDs(ctx, []uint64{id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := st.GetChunkMetaByIDs(ctx, []uint64{id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(full) != 1 || len(meta) != 1 {
+		t.Fatalf("expected one chunk from each, got %d and %d", len(full), len(meta))
+	}
+	if len(full[0].Vec) != 4 {
+		t.Errorf("GetChunksByIDs: expected a 4-dim vector, got %v", full[0].Vec)
+	}
+	if meta[0].Vec != nil {
+		t.Errorf("GetChunkMetaByIDs: expected no vector, got %v", meta[0].Vec)
+	}
+	if meta[0].Content != "alpha #db" || meta[0].CategoryWeight != 1.2 || len(meta[0].Tags) != 2 ||
+		len(meta[0].ChunkTags) != 1 {
+		t.Errorf("metadata not populated: %+v", meta[0].Chunk)
+	}
+}
+
+func TestGetEmbeddingsByInputHash(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+
+	tx, err := st.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	put := func(hash, model string, vec []float32) {
+		id, err := st.InsertChunk(ctx, tx, &Chunk{Path: "/vault/a.md", Content: hash, ContentSHA256: hash})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := st.InsertEmbedding(ctx, tx, &Embedding{ChunkID: id, Dim: len(vec), Vec: vec, Model: model, InputSHA256: hash}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("h1", "m", []float32{1, 0, 0, 0})
+	put("h1", "m", []float32{0, 1, 0, 0}) // newer copy of the same content wins
+	put("h2", "other", []float32{0, 0, 1, 0})
+	put("h3", "", []float32{0, 0, 0, 1}) // model not recorded
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := st.GetEmbeddingsByInputHash(ctx, "m", []string{"h1", "h2", "h3", "h4"})
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if len(got) != 1 || len(got["h1"]) != 4 || got["h1"][1] != 1 {
+		t.Errorf("expected only the newest h1 vector, got %v", got)
+	}
+}
+
+// A database created before embeddings.model existed gains the column on
+// open and keeps its rows.
+func TestOpenAddsEmbeddingModelColumn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE embeddings (
+	  chunk_id INTEGER PRIMARY KEY, dim INTEGER NOT NULL, vec BLOB NOT NULL
+	);
+	INSERT INTO embeddings VALUES (1, 4, x'00000000000000000000000000000000')`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	st, err := Open(path, 4)
+	if err != nil {
+		t.Fatalf("open old database: %v", err)
+	}
+	defer st.Close()
+
+	var n int
+	if err := st.db.QueryRow("SELECT COUNT(*) FROM embeddings WHERE model IS NULL").Scan(&n); err != nil {
+		t.Fatalf("query model column: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected the existing row with a NULL model, got %d rows", n)
+	}
+}
@@ -0,0 +1,74 @@
+-- Fixture: an unversioned database whose embeddings.model and
+-- chunks.deactivated_at_unix columns were added on open, before versioning
+
+-- Files table: tracks processed files to avoid reprocessing unchanged content
+CREATE TABLE IF NOT EXISTS files (
+  path TEXT PRIMARY KEY,
+  sha256 TEXT NOT NULL,
+  mtime_unix INTEGER NOT NULL,
+  indexed_at_unix INTEGER NOT NULL
+);
+
+-- Chunks table: markdown chunks with metadata
+CREATE TABLE IF NOT EXISTS chunks (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  path TEXT NOT NULL,
+  heading_path TEXT,
+  chunk_index INTEGER NOT NULL,
+  content TEXT NOT NULL,
+  content_sha256 TEXT NOT NULL,
+  start_line INTEGER,
+  end_line INTEGER,
+  active INTEGER NOT NULL DEFAULT 1,
+  created_at_unix INTEGER NOT NULL,
+  -- Metadata fields
+  status TEXT,
+  scope TEXT,
+  note_type TEXT,
+  category_weight REAL DEFAULT 1.0,
+  tags TEXT  -- JSON array of tags
+);
+
+-- Embeddings table: raw vectors for chunks
+CREATE TABLE IF NOT EXISTS embeddings (
+  chunk_id INTEGER PRIMARY KEY,
+  dim INTEGER NOT NULL,
+  vec BLOB NOT NULL,
+  FOREIGN KEY(chunk_id) REFERENCES chunks(id) ON DELETE CASCADE
+);
+
+-- Index metadata: tracks HNSW index state
+CREATE TABLE IF NOT EXISTS index_meta (
+  key TEXT PRIMARY KEY,
+  value TEXT NOT NULL
+);
+
+-- Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_chunks_path ON chunks(path);
+CREATE INDEX IF NOT EXISTS idx_chunks_active ON chunks(active);
+CREATE INDEX IF NOT EXISTS idx_chunks_status ON chunks(status);
+CREATE INDEX IF NOT EXISTS idx_files_mtime ON files(mtime_unix);
+
+CREATE TABLE IF NOT EXISTS chunk_changes (
+  seq INTEGER PRIMARY KEY AUTOINCREMENT,
+  chunk_id INTEGER NOT NULL,
+  op TEXT NOT NULL,
+  changed_at_unix INTEGER NOT NULL
+);
+
+ALTER TABLE embeddings ADD COLUMN model TEXT;
+ALTER TABLE chunks ADD COLUMN deactivated_at_unix INTEGER;
+CREATE INDEX IF NOT EXISTS idx_chunks_content_sha256 ON chunks(content_sha256);
+
+-- Sample data: one note, re-indexed once (chunk 1 inactive)
+INSERT INTO files VALUES ('/vault/a.md', 'filehash', 1700000000, 1700000000);
+INSERT INTO chunks (id, path, heading_path, chunk_index, content, content_sha256, start_line, end_line, active, created_at_unix, status, scope, note_type, category_weight, tags)
+  VALUES (1, '/vault/a.md', 'A', 0, 'old body', 'h-old', 1, 3, 0, 1700000000, 'active', 'project', 'note', 1.0, '["go"]'),
+         (2, '/vault/a.md', 'A', 0, 'new body', 'h-new', 1, 3, 1, 1700000100, 'active', 'project', 'note', 1.0, '["go"]');
+INSERT INTO embeddings (chunk_id, dim, vec) VALUES
+  (1, 4, x'0000803f000000000000000000000000'),
+  (2, 4, x'000000000000803f0000000000000000');
+INSERT INTO chunk_changes (chunk_id, op, changed_at_unix) VALUES
+  (1, 'add', 1700000000), (1, 'remove', 1700000100), (2, 'add', 1700000100);
+UPDATE chunks SET deactivated_at_unix = 1700000100 WHERE id = 1;
+UPDATE embeddings SET model = 'ollama-nomic-embed-text';
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -15,11 +16,22 @@ import (
 type FileWatcher struct {
 	watcher  *fsnotify.Watcher
 	onChange func(path string)
+	onRemove func(path string, isDir bool)
 	debounce time.Duration
+
+	// dirs tracks watched directories so a Remove/Rename event (after
+	// which the path can no longer be stat'ed) can be told apart from a
+	// file event. Only touched from the Watch goroutine.
+	dirs map[string]bool
+
+	mu      sync.Mutex
+	pending map[string]*time.Timer // debounce timers by path
 }
 
-// New creates a new file watcher
-func New(onChange func(path string), debounce time.Duration) (*FileWatcher, error) {
+// New creates a new file watcher. onChange fires for created or modified
+// markdown files; onRemove fires for deleted or renamed-away markdown files
+// and directories.
+func New(onChange func(path string), onRemove func(path string, isDir bool), debounce time.Duration) (*FileWatcher, error) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, err
@@ -28,7 +40,10 @@ func New(onChange func(path string), debounce time.Duration) (*FileWatcher, erro
 	return &FileWatcher{
 		watcher:  watcher,
 		onChange: onChange,
+		onRemove: onRemove,
 		debounce: debounce,
+		dirs:     make(map[string]bool),
+		pending:  make(map[string]*time.Timer),
 	}, nil
 }
 
@@ -39,9 +54,6 @@ func (fw *FileWatcher) Watch(ctx context.Context, rootDir string) error {
 		return err
 	}
 
-	// Debounce map: path -> timer
-	pending := make(map[string]*time.Timer)
-
 	for {
 		select {
 		case <-ctx.Done():
@@ -52,14 +64,30 @@ func (fw *FileWatcher) Watch(ctx context.Context, rootDir string) error {
 				return nil
 			}
 
-			// Handle directory creation (need to add to watcher)
+			// Handle directory creation (need to add to watcher). A
+			// directory can arrive already populated (moved in from
+			// elsewhere), so its notes are indexed too.
 			if event.Op&fsnotify.Create == fsnotify.Create {
 				info, err := os.Stat(event.Name)
 				if err == nil && info.IsDir() {
 					if err := fw.addRecursive(event.Name); err != nil {
 						log.Printf("Error adding new directory to watch: %v\n", err)
 					}
+					fw.scheduleExisting(event.Name)
+					continue
+				}
+			}
+
+			// Handle deletions and renames. fsnotify reports a rename as
+			// Rename on the old path plus Create on the new one.
+			if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				if fw.dirs[event.Name] {
+					fw.forgetDir(event.Name)
+					fw.scheduleRemove(event.Name, true)
+				} else if strings.HasSuffix(event.Name, ".md") {
+					fw.scheduleRemove(event.Name, false)
 				}
+				continue
 			}
 
 			// Only care about markdown files
@@ -73,18 +101,7 @@ func (fw *FileWatcher) Watch(ctx context.Context, rootDir string) error {
 				continue
 			}
 
-			path := event.Name
-
-			// Cancel existing timer if any
-			if timer, exists := pending[path]; exists {
-				timer.Stop()
-			}
-
-			// Set new debounce timer
-			pending[path] = time.AfterFunc(fw.debounce, func() {
-				delete(pending, path)
-				fw.onChange(path)
-			})
+			fw.scheduleChange(event.Name)
 
 		case err, ok := <-fw.watcher.Errors:
 			if !ok {
@@ -95,6 +112,76 @@ func (fw *FileWatcher) Watch(ctx context.Context, rootDir string) error {
 	}
 }
 
+// scheduleChange (re)starts the debounce timer for a modified file
+func (fw *FileWatcher) scheduleChange(path string) {
+	fw.schedule(path, fw.debounce, func() { fw.onChange(path) })
+}
+
+// scheduleRemove debounces a removal for twice as long as a change, so the
+// Create half of a rename (or an editor's delete-and-recreate save) lands
+// first. If the path exists again when the timer fires it is treated as a
+// change instead.
+func (fw *FileWatcher) scheduleRemove(path string, isDir bool) {
+	fw.schedule(path, 2*fw.debounce, func() {
+		if _, err := os.Stat(path); err == nil {
+			if !isDir {
+				fw.onChange(path)
+			}
+			return
+		}
+		fw.onRemove(path, isDir)
+	})
+}
+
+// schedule replaces any pending timer for path with fn after delay
+func (fw *FileWatcher) schedule(path string, delay time.Duration, fn func()) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	// Cancel existing timer if any
+	if timer, exists := fw.pending[path]; exists {
+		timer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		fw.mu.Lock()
+		if fw.pending[path] == timer {
+			delete(fw.pending, path)
+		}
+		fw.mu.Unlock()
+		fn()
+	})
+	fw.pending[path] = timer
+}
+
+// scheduleExisting queues every markdown file under dir for indexing
+func (fw *FileWatcher) scheduleExisting(dir string) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() && strings.HasPrefix(info.Name(), ".") && path != dir {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ".md") {
+			fw.scheduleChange(path)
+		}
+		return nil
+	})
+}
+
+// forgetDir drops dir and its subdirectories from the tracked set
+// (fsnotify removes the watches itself once the directory is gone)
+func (fw *FileWatcher) forgetDir(dir string) {
+	prefix := dir + string(filepath.Separator)
+	for d := range fw.dirs {
+		if d == dir || strings.HasPrefix(d, prefix) {
+			delete(fw.dirs, d)
+		}
+	}
+}
+
 // addRecursive recursively adds a directory and all subdirectories to the watcher
 func (fw *FileWatcher) addRecursive(root string) error {
 	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
@@ -111,6 +198,7 @@ func (fw *FileWatcher) addRecursive(root string) error {
 			if err := fw.watcher.Add(path); err != nil {
 				return err
 			}
+			fw.dirs[path] = true
 		}
 		return nil
 	})